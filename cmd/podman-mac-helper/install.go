@@ -15,6 +15,7 @@ import (
 	"syscall"
 	"text/template"
 
+	"github.com/containers/podman/v4/pkg/machine/machelper"
 	"github.com/spf13/cobra"
 )
 
@@ -70,16 +71,26 @@ type launchParams struct {
 	Target  string
 }
 
+// socketPathEnv can be set instead of passing --socket-path to install
+const socketPathEnv = "CONTAINERS_MACHINE_SOCKET"
+
+var (
+	socketPath string
+	rootful    bool
+)
+
 var installCmd = &cobra.Command{
 	Use:    "install",
 	Short:  "installs the podman helper agent",
-	Long:   "installs the podman helper agent, which manages the /var/run/docker.sock link",
+	Long:   "installs the podman helper agent, which manages the /var/run/docker.sock link\n\nWhen the machines are relocated with CONTAINERS_MACHINE_DIR, it has to be set for install too, e.g. with sudo --preserve-env=CONTAINERS_MACHINE_DIR",
 	PreRun: silentUsage,
 	RunE:   install,
 }
 
 func init() {
 	addPrefixFlag(installCmd)
+	installCmd.Flags().StringVar(&socketPath, "socket-path", "", "Sets the socket /var/run/docker.sock points at (default podman.sock in the machine data directory)")
+	installCmd.Flags().BoolVar(&rootful, "rootful", false, "Points /var/run/docker.sock at the socket of rootful machines (podman-root.sock in the machine data directory)")
 	rootCmd.AddCommand(installCmd)
 }
 
@@ -89,8 +100,7 @@ func install(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	labelName := fmt.Sprintf("com.github.containers.podman.helper-%s.plist", userName)
-	fileName := filepath.Join("/Library", "LaunchDaemons", labelName)
+	fileName := machelper.PlistFile(userName)
 
 	if _, err := os.Stat(fileName); err == nil || !os.IsNotExist(err) {
 		fmt.Fprintln(os.Stderr, "helper is already installed, skipping the install, uninstall first if you want to reinstall")
@@ -102,7 +112,10 @@ func install(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	target := filepath.Join(homeDir, ".local", "share", "containers", "podman", "machine", "podman.sock")
+	target, err := installTarget(homeDir)
+	if err != nil {
+		return err
+	}
 	var buf bytes.Buffer
 	t := template.Must(template.New("launchdConfig").Parse(launchConfig))
 	err = t.Execute(&buf, launchParams{prog, userName, uid, target})
@@ -121,12 +134,52 @@ func install(cmd *cobra.Command, args []string) error {
 	}
 
 	if err = runDetectErr("launchctl", "load", fileName); err != nil {
-		return fmt.Errorf("launchctl failed loading service: %w", err)
+		return fmt.Errorf("launchctl failed loading service with Target %s: %w", target, err)
 	}
 
 	return nil
 }
 
+// installTarget returns the socket the service links docker.sock to, which
+// is taken from --socket-path or CONTAINERS_MACHINE_SOCKET when set. With
+// --rootful it is the user global socket of rootful machines. Custom
+// paths outside of the user's home directory must have a root owned parent,
+// so another user can not substitute the socket.
+func installTarget(homeDir string) (string, error) {
+	machineDir := machelper.MachineDir(homeDir)
+	if _, err := os.Stat(machineDir); err != nil {
+		// sudo drops CONTAINERS_MACHINE_DIR unless told to keep it
+		fmt.Fprintf(os.Stderr, "Warning: machine directory %s does not exist; if the machines are relocated with %s, set it for install too\n", machineDir, machelper.MachineDirEnv)
+	}
+	target := socketPath
+	if len(target) == 0 {
+		target = os.Getenv(socketPathEnv)
+	}
+	if rootful {
+		if len(target) > 0 {
+			return "", errors.New("--rootful can not be combined with a custom socket path")
+		}
+		return filepath.Join(machineDir, machelper.RootfulUserSocket), nil
+	}
+	if len(target) == 0 {
+		return filepath.Join(machineDir, machelper.UserSocket), nil
+	}
+
+	if !filepath.IsAbs(target) {
+		return "", fmt.Errorf("socket path for the plist Target must be absolute: %s", target)
+	}
+	target = filepath.Clean(target)
+
+	home := filepath.Clean(homeDir) + string(filepath.Separator)
+	if strings.HasPrefix(target, home) {
+		return target, nil
+	}
+	if err := verifyRootDeep(filepath.Dir(target)); err != nil {
+		return "", fmt.Errorf("socket path %s for the plist Target is outside of %s and its parent is not root owned: %w", target, homeDir, err)
+	}
+	return target, nil
+}
+
 func restrictRecursive(targetDir string, until string) error {
 	for targetDir != until && len(targetDir) > 1 {
 		info, err := os.Lstat(targetDir)
@@ -13,12 +13,13 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/containers/podman/v4/pkg/machine/machelper"
 	"github.com/spf13/cobra"
 )
 
 const (
 	defaultPrefix = "/usr/local"
-	dockerSock    = "/var/run/docker.sock"
+	dockerSock    = machelper.DockerSock
 )
 
 var installPrefix string
@@ -41,6 +42,8 @@ var rootCmd = &cobra.Command{
 //
 // This service is installed once per user and will redirect
 // /var/run/docker to the fixed user-assigned unix socket location.
+// The retarget command can point it elsewhere, but only at a socket
+// owned by the same user.
 //
 // Control communication is restricted to each user specific service via
 // unix file permissions
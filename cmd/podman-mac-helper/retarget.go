@@ -0,0 +1,75 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/containers/podman/v4/pkg/machine/machelper"
+	"github.com/spf13/cobra"
+)
+
+var retargetCmd = &cobra.Command{
+	Use:    "retarget SOCKET",
+	Short:  "points /var/run/docker.sock at a different socket",
+	Long:   "points /var/run/docker.sock at a different socket owned by the user in the machine data directory, such as the API socket of another machine\n\nThe link only lasts until the next machine start, when the service links /var/run/docker.sock back to the socket it was installed with",
+	Args:   cobra.ExactArgs(1),
+	PreRun: silentUsage,
+	RunE:   retarget,
+}
+
+func init() {
+	rootCmd.AddCommand(retargetCmd)
+}
+
+func retarget(cmd *cobra.Command, args []string) error {
+	userName, uid, homeDir, err := getUser()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(machelper.PlistFile(userName)); err != nil {
+		return fmt.Errorf("helper is not installed for %s, run install first", userName)
+	}
+
+	target, err := verifyUserSocket(args[0], uid, machelper.MachineDir(homeDir))
+	if err != nil {
+		return err
+	}
+
+	return linkDockerSock(target)
+}
+
+// verifyUserSocket ensures path is an absolute path to a unix socket owned by
+// uid, so the privileged link can not be pointed at another user's socket.
+// It must be in machineDir too, where status and uninstall recognize links
+// of the helper.
+func verifyUserSocket(path string, uid string, machineDir string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("socket path must be absolute: %s", path)
+	}
+	path = filepath.Clean(path)
+	if !machelper.IsHelperLink(path, "", machineDir) {
+		return "", fmt.Errorf("socket must be in the machine directory %s: %s is not", machineDir, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("could not access socket: %w", err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return "", fmt.Errorf("not a unix socket: %s", path)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	if strconv.FormatUint(uint64(stat.Uid), 10) != uid {
+		return "", fmt.Errorf("socket must be owned by the invoking user: %s is not", path)
+	}
+
+	return path, nil
+}
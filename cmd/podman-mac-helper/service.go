@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"log/syslog"
 	"os"
 	"time"
 
@@ -17,6 +18,11 @@ const (
 	trigger = "GO\n"
 	fail    = "NO"
 	success = "OK"
+
+	// handshakeTimeoutEnv overrides how long the service waits for the trigger
+	handshakeTimeoutEnv     = "PODMAN_HELPER_HANDSHAKE_TIMEOUT"
+	defaultHandshakeTimeout = 5 * time.Second
+	maxHandshakeTimeout     = 60 * time.Second
 )
 
 var serviceCmd = &cobra.Command{
@@ -52,7 +58,9 @@ func service() int {
 	}
 	target := os.Args[2]
 
-	request := make(chan bool)
+	// Buffered so the reader can complete and exit if the timeout
+	// fires first; closing stdin on return unblocks a pending read
+	request := make(chan bool, 1)
 	go func() {
 		buf := make([]byte, 3)
 		_, err := io.ReadFull(os.Stdin, buf)
@@ -62,7 +70,7 @@ func service() int {
 	valid := false
 	select {
 	case valid = <-request:
-	case <-time.After(5 * time.Second):
+	case <-time.After(handshakeTimeout()):
 	}
 
 	if !valid {
@@ -70,12 +78,17 @@ func service() int {
 		return 2
 	}
 
-	err := os.Remove(dockerSock)
-	if err == nil || os.IsNotExist(err) {
-		err = os.Symlink(target, dockerSock)
+	// Refuse to leave docker.sock dangling at a path that was never set up.
+	// The target is usually a link to a machine socket that its forwarder
+	// only creates once the machine runs, so it is not required to be live.
+	if err := checkTarget(target); err != nil {
+		logFailure("not linking %s, target is unusable: %v", dockerSock, err)
+		fmt.Print(fail)
+		return 3
 	}
 
-	if err != nil {
+	if err := linkDockerSock(target); err != nil {
+		logFailure("could not link %s to %s: %v", dockerSock, target, err)
 		fmt.Print(fail)
 		return 3
 	}
@@ -83,3 +96,53 @@ func service() int {
 	fmt.Print(success)
 	return 0
 }
+
+// checkTarget ensures target exists and is a socket or a link, such as the
+// user global link to the socket of a rootful or rootless machine
+func checkTarget(target string) error {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&(fs.ModeSymlink|fs.ModeSocket) == 0 {
+		return fmt.Errorf("%s is not a socket or a link to one", target)
+	}
+	return nil
+}
+
+// logFailure records why a request failed in the system log, since the
+// standard streams are connected to the requesting client
+func logFailure(format string, args ...interface{}) {
+	logger, err := syslog.New(syslog.LOG_ERR|syslog.LOG_DAEMON, "podman-mac-helper")
+	if err != nil {
+		return
+	}
+	defer logger.Close()
+	_ = logger.Err(fmt.Sprintf(format, args...))
+}
+
+// handshakeTimeout returns the time to wait for the trigger, which can be
+// set with PODMAN_HELPER_HANDSHAKE_TIMEOUT and is capped at a minute
+func handshakeTimeout() time.Duration {
+	value, found := os.LookupEnv(handshakeTimeoutEnv)
+	if !found || len(value) == 0 {
+		return defaultHandshakeTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return defaultHandshakeTimeout
+	}
+	if timeout > maxHandshakeTimeout {
+		return maxHandshakeTimeout
+	}
+	return timeout
+}
+
+// linkDockerSock replaces /var/run/docker.sock with a link to target
+func linkDockerSock(target string) error {
+	err := os.Remove(dockerSock)
+	if err == nil || os.IsNotExist(err) {
+		err = os.Symlink(target, dockerSock)
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/containers/podman/v4/pkg/machine/machelper"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:    "status",
+	Short:  "reports the state of the podman helper agent",
+	Long:   "reports whether the podman helper agent is installed and loaded, and where /var/run/docker.sock points",
+	Args:   cobra.NoArgs,
+	PreRun: silentUsage,
+	Run:    statusRun,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+func statusRun(cmd *cobra.Command, args []string) {
+	userName, _, homeDir, err := getUser()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	status, err := machelper.GetStatus(userName, machelper.MachineDir(homeDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// launchctl list exits non-zero when the label is not loaded
+	loaded := exec.Command("launchctl", "list", machelper.Label(userName)).Run() == nil
+
+	fmt.Printf("installed: %t\n", status.Installed)
+	fmt.Printf("plist: %s\n", status.PlistFile)
+	fmt.Printf("loaded: %t\n", loaded)
+	fmt.Printf("target: %s\n", status.Link)
+	fmt.Printf("resolved: %s\n", status.Target)
+
+	if !status.Installed {
+		os.Exit(1)
+	}
+}
@@ -5,10 +5,12 @@ package main
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 
+	"github.com/containers/podman/v4/pkg/machine/machelper"
 	"github.com/spf13/cobra"
 )
 
@@ -26,13 +28,16 @@ func init() {
 }
 
 func uninstall(cmd *cobra.Command, args []string) error {
-	userName, _, _, err := getUser()
+	userName, _, homeDir, err := getUser()
 	if err != nil {
 		return err
 	}
 
-	labelName := fmt.Sprintf("com.github.containers.podman.helper-%s", userName)
-	fileName := filepath.Join("/Library", "LaunchDaemons", labelName+".plist")
+	labelName := machelper.Label(userName)
+	fileName := machelper.PlistFile(userName)
+
+	// Read before the plist is removed, the target may have been customized
+	target := machelper.PlistTarget(fileName)
 
 	if err = runDetectErr("launchctl", "unload", fileName); err != nil {
 		// Try removing the service by label in case the service is half uninstalled
@@ -55,5 +60,27 @@ func uninstall(cmd *cobra.Command, args []string) error {
 	if err := os.RemoveAll(helperPath); err != nil {
 		return fmt.Errorf("could not remove helper binary path: %s", helperPath)
 	}
+
+	if err := removeDockerSockLink(machelper.MachineDir(homeDir), target); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not remove %s: %s\n", dockerSock, err.Error())
+	}
 	return nil
 }
+
+// removeDockerSockLink removes docker.sock if it is a link created by the
+// helper, pointing at target or into the podman machine directory. Anything
+// else, such as the socket of another docker installation, is left alone.
+func removeDockerSockLink(machineDir string, target string) error {
+	info, err := os.Lstat(dockerSock)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return nil
+	}
+	dest, err := os.Readlink(dockerSock)
+	if err != nil {
+		return err
+	}
+	if !machelper.IsHelperLink(dest, target, machineDir) {
+		return nil
+	}
+	return os.Remove(dockerSock)
+}
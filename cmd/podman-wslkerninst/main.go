@@ -4,9 +4,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"syscall"
 	"time"
 	"unsafe"
@@ -22,6 +24,19 @@ const (
 	MB_DEFBUTTON1  = 0x00000000
 )
 
+const (
+	// Environment variables overriding the retry behavior of the update
+	attemptsEnv = "PODMAN_WSL_KERNEL_INSTALL_ATTEMPTS"
+	backoffEnv  = "PODMAN_WSL_KERNEL_INSTALL_BACKOFF"
+
+	// unattendedEnv enables unattended mode when set to any value
+	unattendedEnv = "PODMAN_WSL_KERNEL_INSTALL_UNATTENDED"
+
+	defaultAttempts = 5
+	defaultBackoff  = 500 * time.Millisecond
+	maxBackoff      = 30 * time.Second
+)
+
 const KernelWarning = "WSL Kernel installation did not complete successfully. " +
 	"Podman machine will attempt to install this at a later time. " +
 	"You can also manually complete the installation using the " +
@@ -42,23 +57,53 @@ func setupLogging(name string) (*eventlog.Log, error) {
 	return log, nil
 }
 
+// retrySettings returns the number of update attempts and the delay before
+// the first retry, as overridden by the environment
+func retrySettings() (int, time.Duration) {
+	attempts := defaultAttempts
+	if value := os.Getenv(attemptsEnv); len(value) > 0 {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			attempts = n
+		} else {
+			logrus.Warnf("Ignoring invalid %s value %q", attemptsEnv, value)
+		}
+	}
+
+	backoff := defaultBackoff
+	if value := os.Getenv(backoffEnv); len(value) > 0 {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			backoff = d
+		} else {
+			logrus.Warnf("Ignoring invalid %s value %q", backoffEnv, value)
+		}
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return attempts, backoff
+}
+
 func installWslKernel() error {
 	logrus.Info("Installing WSL Kernel update")
 	var (
 		err error
 	)
-	backoff := 500 * time.Millisecond
-	for i := 1; i < 6; i++ {
+	attempts, backoff := retrySettings()
+	for i := 1; i <= attempts; i++ {
 		err = wsl.SilentExec("wsl", "--update")
-		if err == nil {
+		if err == nil || i == attempts {
 			break
 		}
 
 		// In case of unusual circumstances (e.g. race with installer actions)
 		// retry a few times
-		logrus.Warn("An error occurred attempting the WSL Kernel update, retrying...")
+		logrus.Warnf("An error occurred attempting the WSL Kernel update (attempt %d of %d), retrying in %s...", i, attempts, backoff)
 		time.Sleep(backoff)
 		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
 	}
 
 	if err != nil {
@@ -87,7 +132,24 @@ func warn(title string, caption string) int {
 func main() {
 	args := os.Args
 	setupLogging(path.Base(args[0]))
-	if wsl.IsWSLInstalled() {
+
+	// Automated provisioning can not dismiss the warning dialog, so in
+	// unattended mode failures are only logged and reflected in the exit code
+	unattendedFlag := flag.Bool("unattended", false, "Do not display a warning dialog on failure, exit non-zero instead")
+	flag.Parse()
+	unattended := *unattendedFlag || len(os.Getenv(unattendedEnv)) > 0
+	status, err := wsl.GetWSLStatus()
+	switch {
+	case err != nil:
+		logrus.Infof("Could not determine the WSL status, attempting the kernel update: %s", err.Error())
+	case status.WSL2Unsupported:
+		// A kernel update does not help when WSL 2 can not run at all
+		logrus.Warnf("Only WSL 1 is available on this system (default version %d), skipping the WSL Kernel update. "+
+			"Enable the Virtual Machine Platform feature and virtualization in the firmware to use podman machine", status.DefaultVersion)
+		return
+	case status.Empty:
+		logrus.Info("WSL did not report a status, attempting the kernel update")
+	case !status.KernelMissing:
 		// nothing to do
 		logrus.Info("WSL Kernel already installed")
 		return
@@ -96,7 +158,11 @@ func main() {
 	result := installWslKernel()
 	if result != nil {
 		logrus.Error(result.Error())
+		if unattended {
+			os.Exit(1)
+		}
 		_ = warn("Podman Setup", KernelWarning)
+		return
 	}
 
 	logrus.Info("WSL Kernel update successful")
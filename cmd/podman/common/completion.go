@@ -1473,6 +1473,13 @@ func AutocompleteWaitCondition(cmd *cobra.Command, args []string, toComplete str
 	return states, cobra.ShellCompDirectiveNoFileComp
 }
 
+// AutocompleteMachineWaitCondition - Autocomplete machine wait condition options.
+// -> "running", "stopped"
+func AutocompleteMachineWaitCondition(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	states := []string{"running", "stopped"}
+	return states, cobra.ShellCompDirectiveNoFileComp
+}
+
 // AutocompleteCgroupManager - Autocomplete cgroup manager options.
 // -> "cgroupfs", "systemd"
 func AutocompleteCgroupManager(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
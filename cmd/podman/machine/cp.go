@@ -0,0 +1,64 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/containers/common/pkg/completion"
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/cmd/podman/utils"
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cpCmd = &cobra.Command{
+		Use:               "cp [options] SRC DEST",
+		Short:             "Copy files between the host and a machine",
+		Long:              "Copy files or directories between the host and a virtual machine. One of SRC and DEST is given as NAME:PATH for a path in the machine NAME.",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              cp,
+		Args:              cobra.ExactArgs(2),
+		Example: `podman machine cp ./config.json myvm:/tmp/config.json
+  podman machine cp myvm:/var/log/messages ./messages`,
+		ValidArgsFunction: completion.AutocompleteDefault,
+	}
+)
+
+var (
+	cpUsername     string
+	cpHostKeyCheck bool
+)
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: cpCmd,
+		Parent:  machineCmd,
+	})
+	flags := cpCmd.Flags()
+	usernameFlagName := "username"
+	flags.StringVar(&cpUsername, usernameFlagName, "", "Username to copy the files as in the VM")
+	_ = cpCmd.RegisterFlagCompletionFunc(usernameFlagName, completion.AutocompleteNone)
+
+	hostKeyCheckFlagName := "host-key-check"
+	flags.BoolVar(&cpHostKeyCheck, hostKeyCheckFlagName, true, "Pin the host key of the machine on first connect and verify it afterwards")
+}
+
+func cp(_ *cobra.Command, args []string) error {
+	vmName, opts, err := machine.ParseCpArgs(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	provider := GetSystemDefaultProvider()
+	vm, err := provider.LoadVMByName(vmName)
+	if err != nil {
+		return fmt.Errorf("vm %s not found: %w", vmName, err)
+	}
+
+	opts.Username = cpUsername
+	opts.NoHostKeyCheck = !cpHostKeyCheck
+	return utils.HandleOSExecError(vm.Cp(vmName, opts))
+}
@@ -0,0 +1,53 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/containers/common/pkg/completion"
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:               "export MACHINE FILE",
+		Short:             "Export a machine to a tarball",
+		Long:              "Export a stopped managed virtual machine, with its configuration and ssh keys, to a tarball",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              exportMachine,
+		Args:              cobra.ExactArgs(2),
+		Example:           `podman machine export podman-machine-default backup.tar`,
+		ValidArgsFunction: autocompleteExport,
+	}
+)
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: exportCmd,
+		Parent:  machineCmd,
+	})
+}
+
+func autocompleteExport(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return getMachines(toComplete)
+	}
+	return completion.AutocompleteDefault(cmd, args, toComplete)
+}
+
+func exportMachine(_ *cobra.Command, args []string) error {
+	vmName, file := args[0], args[1]
+	provider := GetSystemDefaultProvider()
+	vm, err := provider.LoadVMByName(vmName)
+	if err != nil {
+		return err
+	}
+	if err := vm.Export(vmName, file); err != nil {
+		return err
+	}
+	fmt.Printf("Machine %q exported to %s\n", vmName, file)
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/containers/common/pkg/completion"
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/libpod/events"
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importCmd = &cobra.Command{
+		Use:               "import NAME FILE",
+		Short:             "Import a machine from a tarball",
+		Long:              "Create a managed virtual machine from a tarball written by podman machine export",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              importMachine,
+		Args:              cobra.ExactArgs(2),
+		Example:           `podman machine import podman-machine-default backup.tar`,
+		ValidArgsFunction: autocompleteImport,
+	}
+)
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: importCmd,
+		Parent:  machineCmd,
+	})
+}
+
+func autocompleteImport(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 1 {
+		return completion.AutocompleteDefault(cmd, args, toComplete)
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+func importMachine(_ *cobra.Command, args []string) error {
+	vmName, file := args[0], args[1]
+	if err := validateMachineName(vmName); err != nil {
+		return err
+	}
+
+	provider := GetSystemDefaultProvider()
+	lock, err := machine.LockMachine(provider.VMType(), vmName)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if _, err := provider.Import(vmName, file); err != nil {
+		return err
+	}
+	newMachineEvent(events.Init, events.Event{Name: vmName})
+	fmt.Printf("Machine %q imported from %s\n", vmName, file)
+	return nil
+}
@@ -4,11 +4,14 @@
 package machine
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"text/tabwriter"
 
 	"github.com/containers/common/pkg/completion"
 	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/libpod/define"
 	"github.com/containers/podman/v4/libpod/events"
 	"github.com/containers/podman/v4/pkg/machine"
 	"github.com/spf13/cobra"
@@ -29,12 +32,26 @@ var (
 	initOpts           = machine.InitOptions{}
 	defaultMachineName = machine.DefaultMachineName
 	now                bool
+	dryRun             bool
 )
 
 // maxMachineNameSize is set to thirty to limit huge machine names primarily
 // because macOS has a much smaller file size limit.
 const maxMachineNameSize = 30
 
+// validateMachineName rejects names that can not be used for the files,
+// connections, and WSL distributions derived from them, before any work
+// such as downloading an image is done
+func validateMachineName(name string) error {
+	if len(name) > maxMachineNameSize {
+		return fmt.Errorf("machine name %q must be %d characters or less", name, maxMachineNameSize)
+	}
+	if !define.NameRegex.MatchString(name) {
+		return fmt.Errorf("invalid machine name %q: names must start with a letter or digit and may only contain letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
+
 func init() {
 	registry.Commands = append(registry.Commands, registry.CliCommand{
 		Command: initCmd,
@@ -104,12 +121,33 @@ func init() {
 	flags.StringVar(&initOpts.VolumeDriver, VolumeDriverFlagName, "", "Optional volume driver")
 	_ = initCmd.RegisterFlagCompletionFunc(VolumeDriverFlagName, completion.AutocompleteDefault)
 
+	WSLConfFlagName := "wsl-conf"
+	flags.StringVar(&initOpts.WSLConfPath, WSLConfFlagName, "", "Path to a wsl.conf file whose settings are added to the machine (Windows only)")
+	_ = initCmd.RegisterFlagCompletionFunc(WSLConfFlagName, completion.AutocompleteDefault)
+
+	DNSFlagName := "dns"
+	flags.StringSliceVar(&initOpts.DNSServers, DNSFlagName, []string{}, "Set custom DNS servers for the machine (Windows only)")
+	_ = initCmd.RegisterFlagCompletionFunc(DNSFlagName, completion.AutocompleteNone)
+
+	DNSSearchFlagName := "dns-search"
+	flags.StringSliceVar(&initOpts.DNSSearch, DNSSearchFlagName, []string{}, "Set custom DNS search domains for the machine (Windows only)")
+	_ = initCmd.RegisterFlagCompletionFunc(DNSSearchFlagName, completion.AutocompleteNone)
+
+	flags.BoolVar(&initOpts.Offline, "offline", false, "Initialize from a local image without network access (Windows only)")
+
+	flags.BoolVar(&dryRun, "dry-run", false, "Print what init would do without creating the machine")
+
+	flags.BoolVarP(&initOpts.Force, "force", "f", false, "Unregister a leftover WSL distribution with the machine name (Windows only)")
+
 	IgnitionPathFlagName := "ignition-path"
 	flags.StringVar(&initOpts.IgnitionPath, IgnitionPathFlagName, "", "Path to ignition file")
 	_ = initCmd.RegisterFlagCompletionFunc(IgnitionPathFlagName, completion.AutocompleteDefault)
 
 	rootfulFlagName := "rootful"
 	flags.BoolVar(&initOpts.Rootful, rootfulFlagName, false, "Whether this machine should prefer rootful container execution")
+
+	quietFlagName := "quiet"
+	flags.BoolVarP(&initOpts.Quiet, quietFlagName, "q", false, "Suppress machine initialization status output")
 }
 
 func initMachine(cmd *cobra.Command, args []string) error {
@@ -121,11 +159,20 @@ func initMachine(cmd *cobra.Command, args []string) error {
 	provider := GetSystemDefaultProvider()
 	initOpts.Name = defaultMachineName
 	if len(args) > 0 {
-		if len(args[0]) > maxMachineNameSize {
-			return fmt.Errorf("machine name %q must be %d characters or less", args[0], maxMachineNameSize)
+		if err := validateMachineName(args[0]); err != nil {
+			return err
 		}
 		initOpts.Name = args[0]
 	}
+	// The elevated child of a reexec runs while the parent holds the lock
+	var lock *machine.MachineLock
+	if !initOpts.ReExec {
+		lock, err = machine.LockMachine(provider.VMType(), initOpts.Name)
+		if err != nil {
+			return err
+		}
+	}
+	defer lock.Unlock()
 	if _, err := provider.LoadVMByName(initOpts.Name); err == nil {
 		return fmt.Errorf("%s: %w", initOpts.Name, machine.ErrVMAlreadyExists)
 	}
@@ -136,6 +183,14 @@ func initMachine(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if dryRun {
+		plan, err := vm.PlanInit(initOpts)
+		if err != nil {
+			return err
+		}
+		printInitPlan(plan)
+		return nil
+	}
 	if finished, err := vm.Init(initOpts); err != nil || !finished {
 		// Finished = true,  err  = nil  -  Success! Log a message with further instructions
 		// Finished = false, err  = nil  -  The installation is partially complete and podman should
@@ -144,9 +199,18 @@ func initMachine(cmd *cobra.Command, args []string) error {
 		//                                  - a user has chosen to perform their own reboot
 		//                                  - reexec for limited admin operations, returning to parent
 		// Finished = *,     err != nil  -  Exit with an error message
-		return err
+		return installRemediation(err)
 	}
 	newMachineEvent(events.Init, events.Event{Name: initOpts.Name})
+	// start takes the lock itself
+	_ = lock.Unlock()
+	if initOpts.Quiet {
+		if now {
+			startOpts.Quiet = true
+			return start(cmd, args)
+		}
+		return nil
+	}
 	fmt.Println("Machine init complete")
 
 	if now {
@@ -159,3 +223,37 @@ func initMachine(cmd *cobra.Command, args []string) error {
 	fmt.Printf("To start your machine run:\n\n\tpodman machine start%s\n\n", extra)
 	return err
 }
+
+// printInitPlan shows what init would do for --dry-run
+func printInitPlan(plan *machine.InitPlan) {
+	install := plan.Install
+	if len(install) == 0 {
+		install = "none"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Machine:\t%s\n", initOpts.Name)
+	fmt.Fprintf(w, "Image:\t%s\n", plan.Image)
+	fmt.Fprintf(w, "Image cached:\t%t\n", plan.ImageCached)
+	fmt.Fprintf(w, "Distribution:\t%s\n", plan.Distribution)
+	fmt.Fprintf(w, "SSH port:\t%d\n", plan.SSHPort)
+	fmt.Fprintf(w, "Config path:\t%s\n", plan.ConfigPath)
+	fmt.Fprintf(w, "Data path:\t%s\n", plan.DataDir)
+	fmt.Fprintf(w, "Install required:\t%s\n", install)
+	w.Flush()
+}
+
+// installRemediation adds advice on how to resolve the installation
+// failures that providers report with typed errors
+func installRemediation(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, machine.ErrFeatureDisabledByPolicy):
+		return fmt.Errorf("%w\nContact your administrator to have the feature enabled", err)
+	case errors.Is(err, machine.ErrAdminRequired):
+		return fmt.Errorf("%w\nApprove the request for administrator privileges, or run the command from an administrator prompt", err)
+	case errors.Is(err, machine.ErrRebootRequired):
+		return fmt.Errorf("%w\nReboot, then run the command again", err)
+	}
+	return err
+}
@@ -72,13 +72,17 @@ func list(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("listing vms: %w", err)
 	}
 
-	// Sort by last run
+	// Currently running machines first, then by last run, falling back
+	// to the name so the order is stable between invocations
 	sort.Slice(listResponse, func(i, j int) bool {
-		return listResponse[i].LastUp.After(listResponse[j].LastUp)
-	})
-	// Bring currently running machines to top
-	sort.Slice(listResponse, func(i, j int) bool {
-		return listResponse[i].Running
+		a, b := listResponse[i], listResponse[j]
+		if a.Running != b.Running {
+			return a.Running
+		}
+		if !a.LastUp.Equal(b.LastUp) {
+			return a.LastUp.After(b.LastUp)
+		}
+		return a.Name < b.Name
 	})
 
 	if report.IsJSON(listFlag.format) {
@@ -105,11 +109,12 @@ func list(cmd *cobra.Command, args []string) error {
 
 func outputTemplate(cmd *cobra.Command, responses []*entities.ListReporter) error {
 	headers := report.Headers(entities.ListReporter{}, map[string]string{
-		"LastUp":   "LAST UP",
-		"VmType":   "VM TYPE",
-		"CPUs":     "CPUS",
-		"Memory":   "MEMORY",
-		"DiskSize": "DISK SIZE",
+		"LastUp":    "LAST UP",
+		"VmType":    "VM TYPE",
+		"CPUs":      "CPUS",
+		"Memory":    "MEMORY",
+		"DiskSize":  "DISK SIZE",
+		"DiskUsage": "DISK USAGE",
 	})
 
 	rpt := report.New(os.Stdout, cmd.Name())
@@ -178,6 +183,8 @@ func toMachineFormat(vms []*machine.ListResponse) ([]*entities.ListReporter, err
 		response.RemoteUsername = vm.RemoteUsername
 		response.IdentityPath = vm.IdentityPath
 		response.Starting = vm.Starting
+		response.Rootful = vm.Rootful
+		response.DiskUsage = strUint(diskUsage(vm))
 
 		machineResponses = append(machineResponses, response)
 	}
@@ -210,12 +217,27 @@ func toHumanFormat(vms []*machine.ListResponse) ([]*entities.ListReporter, error
 			response.LastUp = units.HumanDuration(time.Since(vm.LastUp)) + " ago"
 		}
 		response.Created = units.HumanDuration(time.Since(vm.CreatedAt)) + " ago"
+		response.Stream = streamName(vm.Stream)
 		response.VMType = vm.VMType
+		response.Port = vm.Port
+		response.RemoteUsername = vm.RemoteUsername
+		response.IdentityPath = vm.IdentityPath
+		response.Rootful = vm.Rootful
 		response.CPUs = vm.CPUs
 		response.Memory = units.HumanSize(float64(vm.Memory))
 		response.DiskSize = units.HumanSize(float64(vm.DiskSize))
+		response.DiskUsage = units.HumanSize(float64(diskUsage(vm)))
 
 		humanResponses = append(humanResponses, response)
 	}
 	return humanResponses, nil
 }
+
+// diskUsage returns the space the machine disk takes up on the host,
+// falling back to its size when the provider can not tell
+func diskUsage(vm *machine.ListResponse) uint64 {
+	if vm.DiskUsage == 0 {
+		return vm.DiskSize
+	}
+	return vm.DiskUsage
+}
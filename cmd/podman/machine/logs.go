@@ -0,0 +1,44 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"os"
+
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsCmd = &cobra.Command{
+		Use:               "logs [MACHINE]",
+		Short:             "Show the system logs of a machine",
+		Long:              "Show the systemd journal of the current boot of a managed virtual machine",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              logs,
+		Args:              cobra.MaximumNArgs(1),
+		Example:           `podman machine logs myvm`,
+		ValidArgsFunction: autocompleteMachine,
+	}
+)
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: logsCmd,
+		Parent:  machineCmd,
+	})
+}
+
+func logs(_ *cobra.Command, args []string) error {
+	vmName := defaultMachineName
+	if len(args) > 0 && len(args[0]) > 0 {
+		vmName = args[0]
+	}
+	provider := GetSystemDefaultProvider()
+	vm, err := provider.LoadVMByName(vmName)
+	if err != nil {
+		return err
+	}
+	return vm.Logs(vmName, os.Stdout)
+}
@@ -5,6 +5,7 @@ package machine
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -13,9 +14,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/containers/common/pkg/config"
 	"github.com/containers/podman/v4/cmd/podman/registry"
 	"github.com/containers/podman/v4/cmd/podman/validate"
 	"github.com/containers/podman/v4/libpod/events"
+	"github.com/containers/podman/v4/pkg/errorhandling"
 	"github.com/containers/podman/v4/pkg/machine"
 	"github.com/containers/podman/v4/pkg/util"
 	"github.com/sirupsen/logrus"
@@ -78,6 +81,71 @@ func getMachines(toComplete string) ([]string, cobra.ShellCompDirective) {
 	return suggestions, cobra.ShellCompDirectiveNoFileComp
 }
 
+// forAllMachines runs op on each machine that want selects, carrying on
+// when it fails for a machine. It then reports which machines were done,
+// as in "started", and which failed, and returns the errors of the failed
+// machines.
+func forAllMachines(provider machine.VirtProvider, done string, want func(*machine.ListResponse) bool, op func(name string) error) error {
+	machines, err := provider.List(machine.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var (
+		succeeded []string
+		failed    []string
+		errs      []error
+	)
+	for _, m := range machines {
+		if !want(m) {
+			continue
+		}
+		if err := op(m.Name); err != nil {
+			failed = append(failed, m.Name)
+			errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+			continue
+		}
+		succeeded = append(succeeded, m.Name)
+	}
+
+	switch {
+	case len(succeeded) == 0 && len(failed) == 0:
+		fmt.Printf("No machines needed to be %s\n", done)
+	case len(failed) == 0:
+		fmt.Printf("Machines %s: %s\n", done, strings.Join(succeeded, ", "))
+	default:
+		if len(succeeded) > 0 {
+			fmt.Printf("Machines %s: %s\n", done, strings.Join(succeeded, ", "))
+		}
+		fmt.Printf("Machines failed: %s\n", strings.Join(failed, ", "))
+	}
+	return errorhandling.JoinErrors(errs)
+}
+
+// lockMachine locks an existing machine and loads it. The name is checked
+// and the machine loaded before the lock is taken, so a mistyped name does
+// not leave a lock file behind. It is loaded again once locked, as another
+// process may have changed it in between.
+func lockMachine(provider machine.VirtProvider, name string) (*machine.MachineLock, machine.VM, error) {
+	if err := validateMachineName(name); err != nil {
+		return nil, nil, err
+	}
+	if _, err := provider.LoadVMByName(name); err != nil {
+		return nil, nil, err
+	}
+	lock, err := machine.LockMachine(provider.VMType(), name)
+	if err != nil {
+		return nil, nil, err
+	}
+	vm, err := provider.LoadVMByName(name)
+	if err != nil {
+		// The machine was removed before the lock was taken
+		_ = lock.Remove()
+		return nil, nil, err
+	}
+	return lock, vm, nil
+}
+
 func initMachineEvents() {
 	sockPaths, err := resolveEventSock()
 	if err != nil {
@@ -141,6 +209,32 @@ func eventSockDir() (string, error) {
 	return filepath.Join(xdg, "podman"), nil
 }
 
+// runHook runs the hook configured for a machine state change, telling
+// it the ssh port and the system connection of the machine
+func runHook(hook string, vm machine.VM, name string) {
+	if !machine.HookConfigured(hook) {
+		return
+	}
+	info := machine.HookInfo{Name: name}
+	inspect, err := vm.Inspect()
+	if err != nil {
+		logrus.Debugf("Could not inspect machine %q for the %s hook: %v", name, hook, err)
+	} else {
+		info.State = inspect.State
+		info.SSHPort = inspect.SSHConfig.Port
+		connection := name
+		if inspect.Rootful {
+			connection += "-root"
+		}
+		if cfg, err := config.ReadCustomConfig(); err == nil {
+			if dest, ok := cfg.Engine.ServiceDestinations[connection]; ok {
+				info.ConnectionURI = dest.URI
+			}
+		}
+	}
+	machine.RunHook(hook, info)
+}
+
 func newMachineEvent(status events.Status, event events.Event) {
 	openEventSock.Do(initMachineEvents)
 
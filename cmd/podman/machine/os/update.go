@@ -0,0 +1,56 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package os
+
+import (
+	"github.com/containers/common/pkg/completion"
+	"github.com/containers/podman/v4/cmd/podman/machine"
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/cmd/podman/validate"
+	"github.com/containers/podman/v4/pkg/machine/os"
+	"github.com/spf13/cobra"
+)
+
+var (
+	updateCmd = &cobra.Command{
+		Use:               "update [options] [NAME]",
+		Short:             "Update the packages of a Podman Machine's OS",
+		Long:              "Upgrade the packages of the OS of an existing VM to their latest versions",
+		PersistentPreRunE: validate.NoOp,
+		Args:              cobra.MaximumNArgs(1),
+		RunE:              update,
+		ValidArgsFunction: completion.AutocompleteNone,
+		Example:           `podman machine os update`,
+	}
+)
+
+var updateRestart bool
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: updateCmd,
+		Parent:  machine.OSCmd,
+	})
+	flags := updateCmd.Flags()
+
+	restartFlagName := "restart"
+	flags.BoolVar(&updateRestart, restartFlagName, false, "Restart VM if recommended to apply the updates")
+}
+
+func update(cmd *cobra.Command, args []string) error {
+	vmName := ""
+	if len(args) == 1 {
+		vmName = args[0]
+	}
+	managerOpts := ManagerOpts{
+		VMName:  vmName,
+		CLIArgs: args,
+		Restart: updateRestart,
+	}
+	osManager, err := NewOSManager(managerOpts)
+	if err != nil {
+		return err
+	}
+	return osManager.Update(os.UpdateOptions{})
+}
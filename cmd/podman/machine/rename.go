@@ -0,0 +1,76 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/libpod/events"
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renameCmd = &cobra.Command{
+		Use:               "rename MACHINE NEW_NAME",
+		Short:             "Rename an existing machine",
+		Long:              "Rename a stopped managed virtual machine, along with its ssh keys and system connections",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              rename,
+		Args:              cobra.ExactArgs(2),
+		Example:           `podman machine rename podman-machine-default devvm`,
+		ValidArgsFunction: autocompleteMachine,
+	}
+)
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: renameCmd,
+		Parent:  machineCmd,
+	})
+}
+
+func rename(_ *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+	if err := validateMachineName(newName); err != nil {
+		return err
+	}
+
+	provider := GetSystemDefaultProvider()
+	// Lock both names, so the machine is not used under its old name nor
+	// created under its new one while it is renamed
+	oldLock, vm, err := lockMachine(provider, oldName)
+	if err != nil {
+		return err
+	}
+	defer oldLock.Unlock()
+	newLock, err := machine.LockMachine(provider.VMType(), newName)
+	if err != nil {
+		return err
+	}
+	defer newLock.Unlock()
+
+	exists, err := provider.IsValidVMName(newName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%s: %w", newName, machine.ErrVMAlreadyExists)
+	}
+
+	if err := vm.Rename(oldName, newName); err != nil {
+		// Nothing took the new name, so its lock file would only be left over
+		_ = newLock.Remove()
+		return err
+	}
+	// The old name is free again, so its lock file would only be left over
+	if err := oldLock.Remove(); err != nil {
+		logrus.Warnf("could not remove the lock of %q: %v", oldName, err)
+	}
+	fmt.Printf("Machine %q renamed to %q\n", oldName, newName)
+	newMachineEvent(events.Rename, events.Event{Name: newName})
+	return nil
+}
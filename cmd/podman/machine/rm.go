@@ -13,6 +13,7 @@ import (
 	"github.com/containers/podman/v4/cmd/podman/registry"
 	"github.com/containers/podman/v4/libpod/events"
 	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -54,20 +55,17 @@ func init() {
 }
 
 func rm(_ *cobra.Command, args []string) error {
-	var (
-		err error
-		vm  machine.VM
-	)
 	vmName := defaultMachineName
 	if len(args) > 0 && len(args[0]) > 0 {
 		vmName = args[0]
 	}
 
 	provider := GetSystemDefaultProvider()
-	vm, err = provider.LoadVMByName(vmName)
+	lock, vm, err := lockMachine(provider, vmName)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 	confirmationMessage, remove, err := vm.Remove(vmName, destroyOptions)
 	if err != nil {
 		return err
@@ -90,6 +88,10 @@ func rm(_ *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	// The name is free again, so its lock file would only be left over
+	if err := lock.Remove(); err != nil {
+		logrus.Warnf("could not remove the lock of %q: %v", vmName, err)
+	}
 	newMachineEvent(events.Remove, events.Event{Name: vmName})
 	err = updateDefaultMachineInConfig(vmName)
 	if err != nil {
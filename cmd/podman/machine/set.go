@@ -75,20 +75,16 @@ func init() {
 }
 
 func setMachine(cmd *cobra.Command, args []string) error {
-	var (
-		vm  machine.VM
-		err error
-	)
-
 	vmName := defaultMachineName
 	if len(args) > 0 && len(args[0]) > 0 {
 		vmName = args[0]
 	}
 	provider := GetSystemDefaultProvider()
-	vm, err = provider.LoadVMByName(vmName)
+	lock, vm, err := lockMachine(provider, vmName)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
 	if cmd.Flags().Changed("rootful") {
 		setOpts.Rootful = &setFlags.Rootful
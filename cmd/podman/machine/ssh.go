@@ -4,6 +4,7 @@
 package machine
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 
@@ -29,7 +30,8 @@ var (
 )
 
 var (
-	sshOpts machine.SSHOptions
+	sshOpts      machine.SSHOptions
+	hostKeyCheck bool
 )
 
 func init() {
@@ -42,6 +44,13 @@ func init() {
 	usernameFlagName := "username"
 	flags.StringVar(&sshOpts.Username, usernameFlagName, "", "Username to use when ssh-ing into the VM.")
 	_ = sshCmd.RegisterFlagCompletionFunc(usernameFlagName, completion.AutocompleteNone)
+
+	hostKeyCheckFlagName := "host-key-check"
+	flags.BoolVar(&hostKeyCheck, hostKeyCheckFlagName, true, "Pin the host key of the machine on first connect and verify it afterwards")
+
+	sshOptFlagName := "ssh-opt"
+	flags.StringArrayVar(&sshOpts.Options, sshOptFlagName, nil, "Extra ssh option in key=value form, such as ProxyJump=host")
+	_ = sshCmd.RegisterFlagCompletionFunc(sshOptFlagName, completion.AutocompleteNone)
 }
 
 func ssh(cmd *cobra.Command, args []string) error {
@@ -92,7 +101,14 @@ func ssh(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	sshOpts.NoHostKeyCheck = !hostKeyCheck
 	err = vm.SSH(vmName, sshOpts)
+	// Exit with the status of the remote command without printing it
+	var exitErr *machine.ExitCodeError
+	if errors.As(err, &exitErr) {
+		registry.SetExitCode(exitErr.Code)
+		return nil
+	}
 	return utils.HandleOSExecError(err)
 }
 
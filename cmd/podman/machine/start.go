@@ -4,7 +4,9 @@
 package machine
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/containers/podman/v4/cmd/podman/registry"
 	"github.com/containers/podman/v4/libpod/events"
@@ -20,10 +22,12 @@ var (
 		PersistentPreRunE: rootlessOnly,
 		RunE:              start,
 		Args:              cobra.MaximumNArgs(1),
-		Example:           `podman machine start myvm`,
+		Example: `podman machine start myvm
+  podman machine start --all`,
 		ValidArgsFunction: autocompleteMachine,
 	}
 	startOpts = machine.StartOptions{}
+	startAll  bool
 )
 
 func init() {
@@ -33,6 +37,9 @@ func init() {
 	})
 
 	flags := startCmd.Flags()
+	allFlagName := "all"
+	flags.BoolVarP(&startAll, allFlagName, "a", false, "Start all machines that are not running")
+
 	noInfoFlagName := "no-info"
 	flags.BoolVar(&startOpts.NoInfo, noInfoFlagName, false, "Suppress informational tips")
 
@@ -41,23 +48,52 @@ func init() {
 }
 
 func start(_ *cobra.Command, args []string) error {
-	var (
-		err error
-		vm  machine.VM
-	)
-
 	startOpts.NoInfo = startOpts.Quiet || startOpts.NoInfo
 
+	provider := GetSystemDefaultProvider()
+	if startAll {
+		if len(args) > 0 {
+			return errors.New("--all can not be combined with a machine name")
+		}
+		var (
+			activeName string
+			skipped    []string
+		)
+		stopped := func(m *machine.ListResponse) bool {
+			if m.Running || m.Starting {
+				return false
+			}
+			// Where only one machine can be active at a time, the rest
+			// are left alone once one is, rather than failing to start
+			if active, name, err := provider.CheckExclusiveActiveVM(); err == nil && active {
+				activeName = name
+				skipped = append(skipped, m.Name)
+				return false
+			}
+			return true
+		}
+		err := forAllMachines(provider, "started", stopped, func(name string) error {
+			return startMachine(provider, name)
+		})
+		if len(skipped) > 0 {
+			fmt.Printf("Machines not started, as only one machine can be active at a time and %q is: %s\n", activeName, strings.Join(skipped, ", "))
+		}
+		return err
+	}
+
 	vmName := defaultMachineName
 	if len(args) > 0 && len(args[0]) > 0 {
 		vmName = args[0]
 	}
+	return startMachine(provider, vmName)
+}
 
-	provider := GetSystemDefaultProvider()
-	vm, err = provider.LoadVMByName(vmName)
+func startMachine(provider machine.VirtProvider, vmName string) error {
+	lock, vm, err := lockMachine(provider, vmName)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
 	active, activeName, cerr := provider.CheckExclusiveActiveVM()
 	if cerr != nil {
@@ -77,5 +113,8 @@ func start(_ *cobra.Command, args []string) error {
 	}
 	fmt.Printf("Machine %q started successfully\n", vmName)
 	newMachineEvent(events.Start, events.Event{Name: vmName})
+	// The hook may run podman machine commands of its own
+	_ = lock.Unlock()
+	runHook(machine.PostStartHook, vm, vmName)
 	return nil
 }
@@ -4,8 +4,10 @@
 package machine
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/containers/common/pkg/completion"
 	"github.com/containers/podman/v4/cmd/podman/registry"
 	"github.com/containers/podman/v4/libpod/events"
 	"github.com/containers/podman/v4/pkg/machine"
@@ -14,15 +16,18 @@ import (
 
 var (
 	stopCmd = &cobra.Command{
-		Use:               "stop [MACHINE]",
+		Use:               "stop [options] [MACHINE]",
 		Short:             "Stop an existing machine",
 		Long:              "Stop a managed virtual machine ",
 		PersistentPreRunE: rootlessOnly,
 		RunE:              stop,
 		Args:              cobra.MaximumNArgs(1),
-		Example:           `podman machine stop myvm`,
+		Example: `podman machine stop myvm
+  podman machine stop --all`,
 		ValidArgsFunction: autocompleteMachine,
 	}
+	stopTimeout uint
+	stopAll     bool
 )
 
 func init() {
@@ -30,27 +35,49 @@ func init() {
 		Command: stopCmd,
 		Parent:  machineCmd,
 	})
+
+	flags := stopCmd.Flags()
+	allFlagName := "all"
+	flags.BoolVarP(&stopAll, allFlagName, "a", false, "Stop all running machines")
+
+	timeoutFlagName := "timeout"
+	flags.UintVarP(&stopTimeout, timeoutFlagName, "t", machine.DefaultStopTimeout, "Seconds to wait for a graceful shutdown before terminating the machine, 0 terminates immediately")
+	_ = stopCmd.RegisterFlagCompletionFunc(timeoutFlagName, completion.AutocompleteNone)
 }
 
 // TODO  Name shouldn't be required, need to create a default vm
 func stop(cmd *cobra.Command, args []string) error {
-	var (
-		err error
-		vm  machine.VM
-	)
+	provider := GetSystemDefaultProvider()
+	if stopAll {
+		if len(args) > 0 {
+			return errors.New("--all can not be combined with a machine name")
+		}
+		running := func(m *machine.ListResponse) bool { return m.Running || m.Starting }
+		return forAllMachines(provider, "stopped", running, func(name string) error {
+			return stopMachine(provider, name)
+		})
+	}
+
 	vmName := defaultMachineName
 	if len(args) > 0 && len(args[0]) > 0 {
 		vmName = args[0]
 	}
-	provider := GetSystemDefaultProvider()
-	vm, err = provider.LoadVMByName(vmName)
+	return stopMachine(provider, vmName)
+}
+
+func stopMachine(provider machine.VirtProvider, vmName string) error {
+	lock, vm, err := lockMachine(provider, vmName)
 	if err != nil {
 		return err
 	}
-	if err := vm.Stop(vmName, machine.StopOptions{}); err != nil {
+	defer lock.Unlock()
+	if err := vm.Stop(vmName, machine.StopOptions{Timeout: &stopTimeout}); err != nil {
 		return err
 	}
 	fmt.Printf("Machine %q stopped successfully\n", vmName)
 	newMachineEvent(events.Stop, events.Event{Name: vmName})
+	// The hook may run podman machine commands of its own
+	_ = lock.Unlock()
+	runHook(machine.PostStopHook, vm, vmName)
 	return nil
 }
@@ -0,0 +1,89 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/containers/common/pkg/completion"
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncCmd = &cobra.Command{
+		Use:               "sync [options] [MACHINE] SOURCE DESTINATION",
+		Short:             "Sync a host directory into a machine",
+		Long:              "Incrementally copy the contents of a host directory into a running virtual machine",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              syncMachine,
+		Args:              cobra.RangeArgs(2, 3),
+		Example: `podman machine sync ./project /home/user/project
+  podman machine sync --exclude node_modules --exclude .git myvm ./project /home/user/project`,
+		ValidArgsFunction: autocompleteMachine,
+	}
+	syncOpts         = machine.SyncOptions{}
+	syncQuiet        bool
+	syncHostKeyCheck bool
+)
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: syncCmd,
+		Parent:  machineCmd,
+	})
+
+	flags := syncCmd.Flags()
+	excludeFlagName := "exclude"
+	flags.StringArrayVar(&syncOpts.Excludes, excludeFlagName, []string{}, "Exclude files and directories matching pattern (can be specified multiple times)")
+	_ = syncCmd.RegisterFlagCompletionFunc(excludeFlagName, completion.AutocompleteNone)
+
+	quietFlagName := "quiet"
+	flags.BoolVarP(&syncQuiet, quietFlagName, "q", false, "Suppress the transfer summary")
+
+	hostKeyCheckFlagName := "host-key-check"
+	flags.BoolVar(&syncHostKeyCheck, hostKeyCheckFlagName, true, "Pin the host key of the machine on first connect and verify it afterwards")
+
+	sshOptFlagName := "ssh-opt"
+	flags.StringArrayVar(&syncOpts.Options, sshOptFlagName, nil, "Extra ssh option in key=value form, such as ProxyJump=host")
+	_ = syncCmd.RegisterFlagCompletionFunc(sshOptFlagName, completion.AutocompleteNone)
+}
+
+func syncMachine(_ *cobra.Command, args []string) error {
+	vmName := defaultMachineName
+	if len(args) == 3 {
+		vmName = args[0]
+		args = args[1:]
+	}
+
+	provider := GetSystemDefaultProvider()
+	vm, err := provider.LoadVMByName(vmName)
+	if err != nil {
+		return err
+	}
+
+	state, err := vm.State(false)
+	if err != nil {
+		return err
+	}
+	if state != machine.Running {
+		return fmt.Errorf("vm %q is not running", vmName)
+	}
+
+	info, err := vm.Inspect()
+	if err != nil {
+		return err
+	}
+
+	syncOpts.NoHostKeyCheck = !syncHostKeyCheck
+	report, err := machine.Sync(info.SSHConfig, args[0], args[1], syncOpts)
+	if err != nil {
+		return err
+	}
+	if !syncQuiet {
+		fmt.Printf("Transferred %d files, skipped %d unchanged files\n", report.Transferred, report.Skipped)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/containers/common/pkg/completion"
+	"github.com/containers/podman/v4/cmd/podman/common"
+	"github.com/containers/podman/v4/cmd/podman/registry"
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitCmd = &cobra.Command{
+		Use:               "wait [options] [MACHINE]",
+		Short:             "Wait for a machine to reach a state",
+		Long:              "Block until a managed virtual machine is running or stopped",
+		PersistentPreRunE: rootlessOnly,
+		RunE:              waitMachine,
+		Args:              cobra.MaximumNArgs(1),
+		Example: `podman machine wait --condition running
+  podman machine wait --condition stopped --timeout 2m myvm`,
+		ValidArgsFunction: autocompleteMachine,
+	}
+)
+
+var waitOpts = struct {
+	Condition string
+	Timeout   string
+}{}
+
+func init() {
+	registry.Commands = append(registry.Commands, registry.CliCommand{
+		Command: waitCmd,
+		Parent:  machineCmd,
+	})
+
+	flags := waitCmd.Flags()
+	conditionFlagName := "condition"
+	flags.StringVar(&waitOpts.Condition, conditionFlagName, machine.Running, "Machine state to wait for (running or stopped)")
+	_ = waitCmd.RegisterFlagCompletionFunc(conditionFlagName, common.AutocompleteMachineWaitCondition)
+
+	timeoutFlagName := "timeout"
+	flags.StringVar(&waitOpts.Timeout, timeoutFlagName, "0", "Maximum time to wait, 0 waits indefinitely")
+	_ = waitCmd.RegisterFlagCompletionFunc(timeoutFlagName, completion.AutocompleteNone)
+}
+
+func waitMachine(cmd *cobra.Command, args []string) error {
+	timeout, err := time.ParseDuration(waitOpts.Timeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout %q: %w", waitOpts.Timeout, err)
+	}
+	if timeout < 0 {
+		return fmt.Errorf("invalid timeout %q: must not be negative", waitOpts.Timeout)
+	}
+
+	vmName := defaultMachineName
+	if len(args) > 0 && len(args[0]) > 0 {
+		vmName = args[0]
+	}
+	provider := GetSystemDefaultProvider()
+	load := func() (machine.VM, error) { return provider.LoadVMByName(vmName) }
+	if _, err := load(); err != nil {
+		return err
+	}
+	return machine.WaitForState(load, waitOpts.Condition, timeout)
+}
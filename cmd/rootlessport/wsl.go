@@ -2,21 +2,46 @@ package main
 
 import (
 	"net"
-	"strings"
 
 	"github.com/containers/common/pkg/machine"
 	rkport "github.com/rootless-containers/rootlesskit/pkg/port"
 )
 
+// splitProtocols are the protocols that have ipv4 and ipv6 only variants,
+// named with a 4 or 6 suffix. The builtin rootlesskit parent driver only
+// forwards tcp and udp, so it rejects sctp specs when they are added,
+// split or not.
+var splitProtocols = map[string]bool{
+	"tcp":  true,
+	"udp":  true,
+	"sctp": true,
+}
+
 // WSL machines do not relay ipv4 traffic to dual-stack ports, simulate instead
 func splitDualStackSpecIfWsl(spec rkport.Spec) []rkport.Spec {
-	specs := []rkport.Spec{spec}
+	if machine.MachineHostType() != machine.Wsl {
+		return []rkport.Spec{spec}
+	}
+
+	// An empty protocol is tcp
+	if spec.Proto == "" {
+		spec.Proto = "tcp"
+	}
 	protocol := spec.Proto
-	if machine.MachineHostType() != machine.Wsl || strings.HasSuffix(protocol, "4") || strings.HasSuffix(protocol, "6") {
+
+	ip := net.ParseIP(spec.ParentIP)
+	// IPv4-mapped addresses such as ::ffff:127.0.0.1 are handled as the
+	// ipv4 address they map
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		spec.ParentIP = ip4.String()
+	}
+
+	specs := []rkport.Spec{spec}
+	if !splitProtocols[protocol] {
 		return specs
 	}
 
-	ip := net.ParseIP(spec.ParentIP)
 	splitLoopback := ip.IsLoopback() && ip.To4() == nil
 	// Map ::1 and 0.0.0.0/:: to ipv4 + ipv6 to simulate dual-stack
 	if ip.IsUnspecified() || splitLoopback {
@@ -29,10 +29,16 @@ func TestDualStackSplit(t *testing.T) {
 		TCP_    = "tcp"
 		TCP4    = "tcp4"
 		TCP6    = "tcp6"
+		SCTP    = "sctp"
+		SCTP4   = "sctp4"
+		SCTP6   = "sctp6"
 		WSL     = "wsl"
 		___     = ""
 		IP6_REG = "2001:0db8:85a3:0000:0000:8a2e:0370:7334"
 		IP4_REG = "10.0.0.1"
+		MAP_ALL = "::ffff:0.0.0.0"
+		MAP__LO = "::ffff:127.0.0.1"
+		MAP_REG = "::ffff:10.0.0.1"
 	)
 
 	tests := []SpecData{
@@ -40,6 +46,11 @@ func TestDualStackSplit(t *testing.T) {
 		{WSL, TCP_, IP4_ALL, 2, TCP4, IP4_ALL, TCP6, IP6_ALL},
 		{WSL, TCP_, IP6_ALL, 2, TCP4, IP4_ALL, TCP6, IP6_ALL},
 		{WSL, TCP_, IP6__LO, 2, TCP4, IP4__LO, TCP6, IP6__LO},
+		{WSL, SCTP, IP4_ALL, 2, SCTP4, IP4_ALL, SCTP6, IP6_ALL},
+		{WSL, SCTP, IP6_ALL, 2, SCTP4, IP4_ALL, SCTP6, IP6_ALL},
+		{WSL, SCTP, IP6__LO, 2, SCTP4, IP4__LO, SCTP6, IP6__LO},
+		{WSL, ___, IP4_ALL, 2, TCP4, IP4_ALL, TCP6, IP6_ALL},
+		{WSL, TCP_, MAP_ALL, 2, TCP4, IP4_ALL, TCP6, IP6_ALL},
 
 		// Non-Split
 		{WSL, TCP_, IP4__LO, 1, TCP_, IP4__LO, "", ""},
@@ -47,6 +58,13 @@ func TestDualStackSplit(t *testing.T) {
 		{WSL, TCP6, IP6__LO, 1, TCP6, IP6__LO, "", ""},
 		{WSL, TCP_, IP4_REG, 1, TCP_, IP4_REG, "", ""},
 		{WSL, TCP_, IP6_REG, 1, TCP_, IP6_REG, "", ""},
+		{WSL, TCP_, MAP__LO, 1, TCP_, IP4__LO, "", ""},
+		{WSL, TCP_, MAP_REG, 1, TCP_, IP4_REG, "", ""},
+		{WSL, TCP4, MAP__LO, 1, TCP4, IP4__LO, "", ""},
+		{WSL, SCTP, IP4__LO, 1, SCTP, IP4__LO, "", ""},
+		{WSL, SCTP4, IP4_ALL, 1, SCTP4, IP4_ALL, "", ""},
+		{WSL, SCTP6, IP6_ALL, 1, SCTP6, IP6_ALL, "", ""},
+		{___, SCTP, IP4_ALL, 1, SCTP, IP4_ALL, "", ""},
 		{___, TCP_, IP4_ALL, 1, TCP_, IP4_ALL, "", ""},
 		{___, TCP_, IP6_ALL, 1, TCP_, IP6_ALL, "", ""},
 		{___, TCP_, IP4__LO, 1, TCP_, IP4__LO, "", ""},
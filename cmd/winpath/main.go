@@ -5,6 +5,7 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -44,12 +45,6 @@ func main() {
 		}
 	}
 
-	// Stay silent since ran from an installer
-	if op == NotSpecified {
-		alert("Usage: " + filepath.Base(os.Args[0]) + " [add|remove]\n\nThis utility adds or removes the podman directory to the Windows Path.")
-		os.Exit(ERR_BAD_ARGS)
-	}
-
 	// Hidden operation as a workaround for the installer
 	if op == Open && len(os.Args) > 2 {
 		if err := winOpenFile(os.Args[2]); err != nil {
@@ -58,12 +53,29 @@ func main() {
 		os.Exit(0)
 	}
 
-	if err := modify(op); err != nil {
+	// A dry run shows the resulting Path instead of writing it. Anything
+	// else after the operation is rejected rather than ignored, so a
+	// mistyped flag does not write the registry.
+	dryRun := false
+	switch {
+	case len(os.Args) == 3 && os.Args[2] == "--dry-run":
+		dryRun = true
+	case len(os.Args) > 2:
+		op = NotSpecified
+	}
+
+	// Stay silent since ran from an installer
+	if op == NotSpecified || op == Open {
+		alert("Usage: " + filepath.Base(os.Args[0]) + " [add|remove] [--dry-run]\n\nThis utility adds or removes the podman directory to the Windows Path.")
+		os.Exit(ERR_BAD_ARGS)
+	}
+
+	if err := modify(op, dryRun); err != nil {
 		os.Exit(OPERATION_FAILED)
 	}
 }
 
-func modify(op operation) error {
+func modify(op operation, dryRun bool) error {
 	exe, err := os.Executable()
 	if err != nil {
 		return err
@@ -75,14 +87,14 @@ func modify(op operation) error {
 	target := filepath.Dir(exe)
 
 	if op == Remove {
-		return removePathFromRegistry(target)
+		return removePathFromRegistry(target, dryRun)
 	}
 
-	return addPathToRegistry(target)
+	return addPathToRegistry(target, dryRun)
 }
 
 // Appends a directory to the Windows Path stored in the registry
-func addPathToRegistry(dir string) error {
+func addPathToRegistry(dir string, dryRun bool) error {
 	k, _, err := registry.CreateKey(registry.CURRENT_USER, Environment, registry.WRITE|registry.READ)
 	if err != nil {
 		return err
@@ -95,20 +107,24 @@ func addPathToRegistry(dir string) error {
 		return err
 	}
 
-	// Is this directory already on the windows path?
-	for _, element := range strings.Split(existing, ";") {
-		if strings.EqualFold(element, dir) {
-			// Path already added
-			return nil
+	if typ == registry.EXPAND_SZ {
+		if err := verifyExpandSafe(dir); err != nil {
+			return err
 		}
 	}
 
-	// If the existing path is empty we don't want to start with a delimiter
-	if len(existing) > 0 {
-		existing += ";"
+	newPath := addToPath(existing, dir)
+	if dryRun {
+		// winpath is built for the GUI subsystem, which has no console
+		// to print to
+		info("The Path would be set to:\n\n" + newPath)
+		return nil
 	}
-
-	existing += dir
+	if newPath == existing {
+		// Path already added
+		return nil
+	}
+	existing = newPath
 
 	// It's important to preserve the registry key type so that it will be interpreted correctly
 	// EXPAND = evaluate variables in the expression, e.g. %PATH% should be expanded to the system path
@@ -126,8 +142,58 @@ func addPathToRegistry(dir string) error {
 	return err
 }
 
+// Appends dir to a semicolon separated path list unless it is already
+// present. Duplicate and empty entries left behind by earlier installs or
+// manual edits are dropped, keeping the first occurrence of each entry.
+func addToPath(existing string, dir string) string {
+	var elements []string
+	found := false
+	for _, element := range strings.Split(existing, ";") {
+		if len(element) == 0 {
+			continue
+		}
+		duplicate := false
+		for _, kept := range elements {
+			if strings.EqualFold(kept, element) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		if strings.EqualFold(element, dir) {
+			found = true
+		}
+		elements = append(elements, element)
+	}
+
+	if !found {
+		elements = append(elements, dir)
+	}
+
+	return strings.Join(elements, ";")
+}
+
+// Verifies dir is not altered by variable expansion. An EXPAND_SZ value has
+// no way to escape a literal %, so a directory such as C:\100%TEMP%\podman
+// can not be appended to it safely.
+func verifyExpandSafe(dir string) error {
+	if !strings.Contains(dir, "%") {
+		return nil
+	}
+	expanded, err := registry.ExpandString(dir)
+	if err != nil {
+		return err
+	}
+	if expanded != dir {
+		return fmt.Errorf("directory %q would be changed to %q by variable expansion in the Path", dir, expanded)
+	}
+	return nil
+}
+
 // Removes all occurrences of a directory path from the Windows path stored in the registry
-func removePathFromRegistry(path string) error {
+func removePathFromRegistry(path string, dryRun bool) error {
 	k, err := registry.OpenKey(registry.CURRENT_USER, Environment, registry.READ|registry.WRITE)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
@@ -153,6 +219,13 @@ func removePathFromRegistry(path string) error {
 	}
 
 	newPath := strings.Join(elements, ";")
+	if dryRun {
+		// winpath is built for the GUI subsystem, which has no console
+		// to print to
+		info("The Path would be set to:\n\n" + newPath)
+		return nil
+	}
+
 	// Preserve value type (see corresponding comment above)
 	if typ == registry.EXPAND_SZ {
 		err = k.SetExpandStringValue("Path", newPath)
@@ -181,8 +254,16 @@ func broadcastEnvironmentChange() {
 // Creates an "error" style pop-up window
 func alert(caption string) int {
 	// Error box style
-	format := 0x10
+	return messageBox(caption, 0x10)
+}
+
+// Creates an "information" style pop-up window
+func info(caption string) int {
+	// Information box style
+	return messageBox(caption, 0x40)
+}
 
+func messageBox(caption string, format int) int {
 	user32 := syscall.NewLazyDLL("user32.dll")
 	captionPtr, _ := syscall.UTF16PtrFromString(caption)
 	titlePtr, _ := syscall.UTF16PtrFromString("winpath")
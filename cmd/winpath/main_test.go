@@ -0,0 +1,94 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddToPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name:     "empty path",
+			existing: "",
+			want:     `C:\podman`,
+		},
+		{
+			name:     "appended",
+			existing: `C:\bin;C:\tools`,
+			want:     `C:\bin;C:\tools;C:\podman`,
+		},
+		{
+			name:     "already present",
+			existing: `C:\bin;c:\PODMAN;C:\tools`,
+			want:     `C:\bin;c:\PODMAN;C:\tools`,
+		},
+		{
+			name:     "duplicates removed keeping first occurrence",
+			existing: `C:\tools;C:\bin;c:\TOOLS;C:\podman;C:\bin;C:\podman`,
+			want:     `C:\tools;C:\bin;C:\podman`,
+		},
+		{
+			name:     "environment references kept",
+			existing: `%USERPROFILE%\bin;%SystemRoot%\system32`,
+			want:     `%USERPROFILE%\bin;%SystemRoot%\system32;C:\podman`,
+		},
+		{
+			name:     "empty entries removed",
+			existing: `C:\bin;;C:\tools;`,
+			want:     `C:\bin;C:\tools;C:\podman`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, addToPath(tt.existing, `C:\podman`))
+		})
+	}
+}
+
+func TestVerifyExpandSafe(t *testing.T) {
+	t.Setenv("PODMAN_WINPATH_TEST", "expanded")
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{
+			name: "no percent",
+			dir:  `C:\Program Files\RedHat\Podman`,
+		},
+		{
+			name: "lone percent",
+			dir:  `C:\100%\podman`,
+		},
+		{
+			name: "undefined variable",
+			dir:  `C:\%PODMAN_WINPATH_UNDEFINED%\podman`,
+		},
+		{
+			name:    "defined variable",
+			dir:     `C:\%PODMAN_WINPATH_TEST%\podman`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyExpandSafe(tt.dir)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
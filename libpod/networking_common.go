@@ -694,21 +694,29 @@ func ocicniPortsToNetTypesPorts(ports []types.OCICNIPortMapping) []types.PortMap
 	}
 
 	for i := 1; i < len(ports); i++ {
+		// ports with the same host to container offset can be merged
+		// into the current range if they extend it or are already
+		// covered by it, e.g. when the same port was given twice
 		if ports[i].HostIP == currentPort.HostIP &&
 			ports[i].Protocol == currentPort.Protocol &&
-			ports[i].HostPort-int32(currentPort.Range) == int32(currentPort.HostPort) &&
-			ports[i].ContainerPort-int32(currentPort.Range) == int32(currentPort.ContainerPort) {
-			currentPort.Range++
-		} else {
-			newPorts = append(newPorts, currentPort)
-			currentPort = types.PortMapping{
-				HostIP:        ports[i].HostIP,
-				HostPort:      uint16(ports[i].HostPort),
-				ContainerPort: uint16(ports[i].ContainerPort),
-				Protocol:      ports[i].Protocol,
-				Range:         1,
+			ports[i].HostPort-ports[i].ContainerPort == int32(currentPort.HostPort)-int32(currentPort.ContainerPort) {
+			end := int32(currentPort.HostPort) + int32(currentPort.Range)
+			if ports[i].HostPort == end {
+				currentPort.Range++
+				continue
+			}
+			if ports[i].HostPort < end {
+				continue
 			}
 		}
+		newPorts = append(newPorts, currentPort)
+		currentPort = types.PortMapping{
+			HostIP:        ports[i].HostIP,
+			HostPort:      uint16(ports[i].HostPort),
+			ContainerPort: uint16(ports[i].ContainerPort),
+			Protocol:      ports[i].Protocol,
+			Range:         1,
+		}
 	}
 	newPorts = append(newPorts, currentPort)
 	return newPorts
@@ -166,6 +166,116 @@ func Test_ocicniPortsToNetTypesPorts(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "adjacent runs of ports are joined",
+			arg: []types.OCICNIPortMapping{
+				{
+					HostPort:      8082,
+					ContainerPort: 82,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8083,
+					ContainerPort: 83,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8084,
+					ContainerPort: 84,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8081,
+					ContainerPort: 81,
+					Protocol:      "tcp",
+				},
+			},
+			want: []types.PortMapping{
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+					Range:         5,
+				},
+			},
+		},
+		{
+			name: "adjacent runs with a different offset are not joined",
+			arg: []types.OCICNIPortMapping{
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8081,
+					ContainerPort: 81,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8082,
+					ContainerPort: 92,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8083,
+					ContainerPort: 93,
+					Protocol:      "tcp",
+				},
+			},
+			want: []types.PortMapping{
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+					Range:         2,
+				},
+				{
+					HostPort:      8082,
+					ContainerPort: 92,
+					Protocol:      "tcp",
+					Range:         2,
+				},
+			},
+		},
+		{
+			name: "duplicate ports are joined",
+			arg: []types.OCICNIPortMapping{
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8081,
+					ContainerPort: 81,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+				},
+				{
+					HostPort:      8082,
+					ContainerPort: 82,
+					Protocol:      "tcp",
+				},
+			},
+			want: []types.PortMapping{
+				{
+					HostPort:      8080,
+					ContainerPort: 80,
+					Protocol:      "tcp",
+					Range:         3,
+				},
+			},
+		},
 		{
 			name: "different protocols ports are not joined",
 			arg: []types.OCICNIPortMapping{
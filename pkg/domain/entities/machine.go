@@ -17,6 +17,8 @@ type ListReporter struct {
 	Port           int
 	RemoteUsername string
 	IdentityPath   string
+	Rootful        bool
+	DiskUsage      string
 }
 
 // MachineInfo contains info on the machine host and version info
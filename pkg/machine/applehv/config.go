@@ -27,6 +27,10 @@ func (v Virtualization) Format() machine.ImageFormat {
 	return v.format
 }
 
+func (v Virtualization) Import(_ string, _ string) (machine.VM, error) {
+	return nil, machine.ErrNotImplemented
+}
+
 func (v Virtualization) IsValidVMName(name string) (bool, error) {
 	return false, machine.ErrNotImplemented
 }
@@ -6,10 +6,12 @@ package machine
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/containers/storage/pkg/homedir"
@@ -31,8 +33,37 @@ type InitOptions struct {
 	Username     string
 	ReExec       bool
 	Rootful      bool
+	Quiet        bool
 	// The numerical userid of the user that called machine
 	UID string
+	// WSLConfPath is a wsl.conf file whose settings are added to the
+	// one written for WSL machines
+	WSLConfPath string
+	// DNSServers and DNSSearch replace the name resolution WSL generates
+	// for WSL machines
+	DNSServers []string
+	DNSSearch  []string
+	// Offline initializes from a local image without network access
+	Offline bool
+	// Force replaces a leftover WSL distribution with the machine name
+	Force bool
+}
+
+// InitPlan describes what initializing a machine would do, as reported by
+// podman machine init --dry-run
+type InitPlan struct {
+	// Image is the URL or local path the machine image is taken from
+	Image string
+	// ImageCached is set when a usable copy of the image is already cached
+	ImageCached bool
+	// Distribution is the name the provider registers the machine under
+	Distribution string
+	SSHPort      int
+	ConfigPath   string
+	DataDir      string
+	// Install names the host component that has to be installed before
+	// the machine can be created, empty when nothing is missing
+	Install string
 }
 
 type Status = string
@@ -52,6 +83,9 @@ type VirtProvider interface {
 	CheckExclusiveActiveVM() (bool, string, error)
 	Compression() ImageCompression
 	Format() ImageFormat
+	Import(name string, path string) (VM, error)
+	// IsValidVMName reports whether a machine called name exists, rather
+	// than whether name could be used for a new machine
 	IsValidVMName(name string) (bool, error)
 	List(opts ListOptions) ([]*ListResponse, error)
 	LoadVMByName(name string) (VM, error)
@@ -71,6 +105,14 @@ var (
 	ErrMultipleActiveVM                          = errors.New("only one VM can be active at a time")
 	ErrNotImplemented                            = errors.New("functionality not implemented")
 	ForwarderBinaryName                          = "gvproxy"
+	// ErrRebootRequired, ErrAdminRequired and ErrFeatureDisabledByPolicy
+	// are returned when installing the host support for a provider, such
+	// as WSL, can not complete
+	ErrRebootRequired          = errors.New("a reboot is required to complete the installation")
+	ErrAdminRequired           = errors.New("administrator privileges are required for the installation")
+	ErrFeatureDisabledByPolicy = errors.New("the feature is disabled by group policy")
+	// ErrWSLNotInstalled is returned when the WSL provider can not find wsl.exe
+	ErrWSLNotInstalled = errors.New("WSL is not installed")
 )
 
 type Download struct {
@@ -104,6 +146,10 @@ type ListResponse struct {
 	Port           int
 	RemoteUsername string
 	IdentityPath   string
+	Rootful        bool
+	// DiskUsage is the space in bytes the disk takes up on the host, which
+	// for sparse images is less than DiskSize. Zero if not known.
+	DiskUsage uint64
 }
 
 type SetOptions struct {
@@ -116,6 +162,12 @@ type SetOptions struct {
 type SSHOptions struct {
 	Username string
 	Args     []string
+	// Options are extra ssh options in the -o form, such as
+	// ProxyJump=bastion
+	Options []string
+	// NoHostKeyCheck accepts any host key instead of pinning the key
+	// of the machine in its known_hosts file
+	NoHostKeyCheck bool
 }
 
 type StartOptions struct {
@@ -123,7 +175,15 @@ type StartOptions struct {
 	Quiet  bool
 }
 
-type StopOptions struct{}
+// DefaultStopTimeout is the number of seconds a machine is given to shut
+// down gracefully before it is terminated
+const DefaultStopTimeout uint = 60
+
+type StopOptions struct {
+	// Timeout is the number of seconds to wait for a graceful shutdown. nil
+	// uses DefaultStopTimeout, 0 skips the graceful shutdown entirely.
+	Timeout *uint
+}
 
 type RemoveOptions struct {
 	Force        bool
@@ -135,14 +195,20 @@ type RemoveOptions struct {
 type InspectOptions struct{}
 
 type VM interface {
+	Cp(name string, opts CpOptions) error
+	Export(name string, path string) error
 	Init(opts InitOptions) (bool, error)
 	Inspect() (*InspectInfo, error)
+	Logs(name string, out io.Writer) error
+	PlanInit(opts InitOptions) (*InitPlan, error)
 	Remove(name string, opts RemoveOptions) (string, func() error, error)
+	Rename(name string, newName string) error
 	Set(name string, opts SetOptions) ([]error, error)
 	SSH(name string, opts SSHOptions) error
 	Start(name string, opts StartOptions) error
 	State(bypass bool) (Status, error)
 	Stop(name string, opts StopOptions) error
+	UpdateOS(name string) (bool, error)
 }
 
 type DistributionDownload interface {
@@ -156,10 +222,13 @@ type InspectInfo struct {
 	Created        time.Time
 	Image          ImageConfig
 	LastUp         time.Time
+	Mounts         []Mount
 	Name           string
 	Resources      ResourceConfig
+	Rootful        bool
 	SSHConfig      SSHConfig
 	State          Status
+	VMType         string
 }
 
 func (rc RemoteConnectionType) MakeSSHURL(host, path, port, userName string) url.URL {
@@ -212,6 +281,9 @@ func GetDataDir(vmType VMType) (string, error) {
 
 // DataDirPrefix returns the path prefix for all machine data files
 func DataDirPrefix() (string, error) {
+	if dir, err := machineDir(); err != nil || len(dir) > 0 {
+		return filepath.Join(dir, "data"), err
+	}
 	data, err := homedir.GetDataHome()
 	if err != nil {
 		return "", err
@@ -237,6 +309,9 @@ func GetConfDir(vmType VMType) (string, error) {
 
 // ConfDirPrefix returns the path prefix for all machine config files
 func ConfDirPrefix() (string, error) {
+	if dir, err := machineDir(); err != nil || len(dir) > 0 {
+		return filepath.Join(dir, "config"), err
+	}
 	conf, err := homedir.GetConfigHome()
 	if err != nil {
 		return "", err
@@ -245,6 +320,46 @@ func ConfDirPrefix() (string, error) {
 	return confDir, nil
 }
 
+// machineDirEnv relocates the configuration and data of all machines,
+// which are kept in its config and data subdirectories
+const machineDirEnv = "CONTAINERS_MACHINE_DIR"
+
+var (
+	checkedMachineDir     string
+	checkedMachineDirLock sync.Mutex
+)
+
+// machineDir returns the directory set with CONTAINERS_MACHINE_DIR after
+// checking that it can be written to, or an empty string when not set
+func machineDir() (string, error) {
+	dir, found := os.LookupEnv(machineDirEnv)
+	if !found || len(dir) == 0 {
+		return "", nil
+	}
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("%s must be an absolute path, got %q", machineDirEnv, dir)
+	}
+
+	checkedMachineDirLock.Lock()
+	defer checkedMachineDirLock.Unlock()
+	if dir == checkedMachineDir {
+		return dir, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("could not create %s directory: %w", machineDirEnv, err)
+	}
+	probe, err := os.CreateTemp(dir, ".podman-write-check")
+	if err != nil {
+		return "", fmt.Errorf("%s directory %q is not writable: %w", machineDirEnv, dir, err)
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return "", err
+	}
+	checkedMachineDir = dir
+	return dir, nil
+}
+
 // GuardedRemoveAll functions much like os.RemoveAll but
 // will not delete certain catastrophic paths.
 func GuardedRemoveAll(path string) error {
@@ -262,6 +377,9 @@ type ResourceConfig struct {
 	DiskSize uint64
 	// Memory in megabytes assigned to the vm
 	Memory uint64
+	// DiskUsage is the space in bytes the disk takes up on the host, if
+	// known
+	DiskUsage uint64 `json:",omitempty"`
 }
 
 const maxSocketPathLength int = 103
@@ -6,8 +6,11 @@ package machine
 import (
 	"net"
 	"net/url"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRemoteConnectionType_MakeSSHURL(t *testing.T) {
@@ -72,3 +75,27 @@ func TestRemoteConnectionType_MakeSSHURL(t *testing.T) {
 		})
 	}
 }
+
+func TestMachineDirEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(machineDirEnv, dir)
+
+	confDir, err := GetConfDir(WSLVirt)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "config", "wsl"), confDir)
+	assert.DirExists(t, confDir)
+
+	dataDir, err := GetDataDir(WSLVirt)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "data", "wsl"), dataDir)
+
+	t.Setenv(machineDirEnv, "relative/machines")
+	_, err = ConfDirPrefix()
+	assert.ErrorContains(t, err, "must be an absolute path")
+
+	t.Setenv(machineDirEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	confPrefix, err := ConfDirPrefix()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "containers", "podman", "machine"), confPrefix)
+}
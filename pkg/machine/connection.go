@@ -6,10 +6,21 @@ package machine
 import (
 	"errors"
 	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/containers/common/pkg/config"
+	"github.com/containers/podman/v4/utils"
 )
 
+// sshPortRangeEnv restricts the host ports picked for machine ssh
+// connections to an inclusive range such as 50000-50100
+const sshPortRangeEnv = "PODMAN_MACHINE_SSH_PORT_RANGE"
+
 func AddConnection(uri fmt.Stringer, name, identity string, isDefault bool) error {
 	if len(identity) < 1 {
 		return errors.New("identity must be defined")
@@ -77,3 +88,131 @@ func RemoveConnection(name string) error {
 	}
 	return cfg.Write()
 }
+
+// ChangeConnectionPort rewrites the port of the named connections, used
+// when a machine's ssh port had to be reassigned. Names without a
+// connection are ignored.
+func ChangeConnectionPort(port int, names ...string) error {
+	cfg, err := config.ReadCustomConfig()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		dst, ok := cfg.Engine.ServiceDestinations[name]
+		if !ok {
+			continue
+		}
+		uri, err := url.Parse(dst.URI)
+		if err != nil {
+			return fmt.Errorf("could not parse connection %q: %w", name, err)
+		}
+		uri.Host = net.JoinHostPort(uri.Hostname(), strconv.Itoa(port))
+		dst.URI = uri.String()
+		cfg.Engine.ServiceDestinations[name] = dst
+	}
+	return cfg.Write()
+}
+
+// RenameConnections moves the user and root connections of a machine to
+// the names of its new name, pointing them at the renamed identity. The
+// default connection follows the rename.
+func RenameConnections(oldName, newName, identity string) error {
+	cfg, err := config.ReadCustomConfig()
+	if err != nil {
+		return err
+	}
+	if err := checkRenameConnections(cfg, oldName, newName); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"", "-root"} {
+		dst, ok := cfg.Engine.ServiceDestinations[oldName+suffix]
+		if !ok {
+			continue
+		}
+		dst.Identity = identity
+		delete(cfg.Engine.ServiceDestinations, oldName+suffix)
+		cfg.Engine.ServiceDestinations[newName+suffix] = dst
+		if cfg.Engine.ActiveService == oldName+suffix {
+			cfg.Engine.ActiveService = newName + suffix
+		}
+	}
+	return cfg.Write()
+}
+
+// CheckRenameConnections returns an error if RenameConnections would have
+// to overwrite an existing connection
+func CheckRenameConnections(oldName, newName string) error {
+	cfg, err := config.ReadCustomConfig()
+	if err != nil {
+		return err
+	}
+	return checkRenameConnections(cfg, oldName, newName)
+}
+
+func checkRenameConnections(cfg *config.Config, oldName, newName string) error {
+	for _, suffix := range []string{"", "-root"} {
+		if _, ok := cfg.Engine.ServiceDestinations[oldName+suffix]; !ok {
+			continue
+		}
+		if _, ok := cfg.Engine.ServiceDestinations[newName+suffix]; ok {
+			return fmt.Errorf("cannot overwrite connection %q", newName+suffix)
+		}
+	}
+	return nil
+}
+
+// IsLocalPortAvailable reports whether port can be bound on the loopback
+// interface
+func IsLocalPortAvailable(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// AllocateSSHPort returns a free local port for the ssh connection of a
+// machine. Any free port is used unless PODMAN_MACHINE_SSH_PORT_RANGE is
+// set, in which case the port is picked from that range.
+func AllocateSSHPort() (int, error) {
+	value, found := os.LookupEnv(sshPortRangeEnv)
+	if !found || len(value) == 0 {
+		return utils.GetRandomPort()
+	}
+	low, high, err := parsePortRange(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", sshPortRangeEnv, err)
+	}
+
+	// Start at a random offset so machines created together do not all
+	// probe the same ports
+	size := high - low + 1
+	offset := rand.Intn(size)
+	for i := 0; i < size; i++ {
+		port := low + (offset+i)%size
+		if IsLocalPortAvailable(port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port in the %s range %d-%d", sshPortRangeEnv, low, high)
+}
+
+func parsePortRange(value string) (int, int, error) {
+	first, last, found := strings.Cut(value, "-")
+	if !found {
+		last = first
+	}
+	low, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a port range", value)
+	}
+	high, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a port range", value)
+	}
+	if low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("%q must be a range of ports between 1 and 65535", value)
+	}
+	return low, high, nil
+}
@@ -0,0 +1,132 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/containers/common/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeConnectionPort(t *testing.T) {
+	t.Setenv("CONTAINERS_CONF", filepath.Join(t.TempDir(), "containers.conf"))
+
+	user := SSHRemoteConnection.MakeSSHURL("localhost", "/run/user/1000/podman/podman.sock", "2222", "user")
+	root := SSHRemoteConnection.MakeSSHURL("localhost", "/run/podman/podman.sock", "2222", "root")
+	require.NoError(t, AddConnection(&user, "vm", "/id", true))
+	require.NoError(t, AddConnection(&root, "vm-root", "/id", false))
+
+	require.NoError(t, ChangeConnectionPort(4444, "vm", "vm-root", "missing"))
+
+	cfg, err := config.ReadCustomConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "ssh://user@localhost:4444/run/user/1000/podman/podman.sock", cfg.Engine.ServiceDestinations["vm"].URI)
+	assert.Equal(t, "ssh://root@localhost:4444/run/podman/podman.sock", cfg.Engine.ServiceDestinations["vm-root"].URI)
+	assert.Equal(t, "/id", cfg.Engine.ServiceDestinations["vm"].Identity)
+	assert.Equal(t, "vm", cfg.Engine.ActiveService)
+	assert.NotContains(t, cfg.Engine.ServiceDestinations, "missing")
+}
+
+func TestIsLocalPortAvailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.False(t, IsLocalPortAvailable(port))
+
+	require.NoError(t, l.Close())
+	assert.True(t, IsLocalPortAvailable(port))
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantLow  int
+		wantHigh int
+		wantErr  bool
+	}{
+		{value: "50000-50100", wantLow: 50000, wantHigh: 50100},
+		{value: " 50000 - 50000 ", wantLow: 50000, wantHigh: 50000},
+		{value: "2222", wantLow: 2222, wantHigh: 2222},
+		{value: "50100-50000", wantErr: true},
+		{value: "0-100", wantErr: true},
+		{value: "60000-70000", wantErr: true},
+		{value: "high-low", wantErr: true},
+		{value: "-", wantErr: true},
+	}
+	for _, tt := range tests {
+		low, high, err := parsePortRange(tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.wantLow, low, tt.value)
+		assert.Equal(t, tt.wantHigh, high, tt.value)
+	}
+}
+
+func TestAllocateSSHPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	t.Setenv(sshPortRangeEnv, strconv.Itoa(busy))
+	_, err = AllocateSSHPort()
+	assert.Error(t, err)
+
+	t.Setenv(sshPortRangeEnv, fmt.Sprintf("%d-%d", busy, busy+1))
+	port, err := AllocateSSHPort()
+	if IsLocalPortAvailable(busy + 1) {
+		require.NoError(t, err)
+		assert.Equal(t, busy+1, port)
+	}
+
+	t.Setenv(sshPortRangeEnv, "")
+	port, err = AllocateSSHPort()
+	require.NoError(t, err)
+	assert.NotZero(t, port)
+}
+
+func TestRenameConnections(t *testing.T) {
+	t.Setenv("CONTAINERS_CONF", filepath.Join(t.TempDir(), "containers.conf"))
+
+	user := SSHRemoteConnection.MakeSSHURL("localhost", "/run/user/1000/podman/podman.sock", "2222", "user")
+	root := SSHRemoteConnection.MakeSSHURL("localhost", "/run/podman/podman.sock", "2222", "root")
+	require.NoError(t, AddConnection(&root, "vm-root", "/ssh/vm", true))
+	require.NoError(t, AddConnection(&user, "vm", "/ssh/vm", false))
+
+	require.NoError(t, RenameConnections("vm", "dev", "/ssh/dev"))
+
+	cfg, err := config.ReadCustomConfig()
+	require.NoError(t, err)
+	assert.NotContains(t, cfg.Engine.ServiceDestinations, "vm")
+	assert.NotContains(t, cfg.Engine.ServiceDestinations, "vm-root")
+	assert.Equal(t, user.String(), cfg.Engine.ServiceDestinations["dev"].URI)
+	assert.Equal(t, root.String(), cfg.Engine.ServiceDestinations["dev-root"].URI)
+	assert.Equal(t, "/ssh/dev", cfg.Engine.ServiceDestinations["dev-root"].Identity)
+	assert.Equal(t, "dev-root", cfg.Engine.ActiveService)
+}
+
+func TestCheckRenameConnections(t *testing.T) {
+	t.Setenv("CONTAINERS_CONF", filepath.Join(t.TempDir(), "containers.conf"))
+
+	uri := SSHRemoteConnection.MakeSSHURL("localhost", "/run/podman/podman.sock", "2222", "root")
+	require.NoError(t, AddConnection(&uri, "vm-root", "/ssh/vm", true))
+	assert.NoError(t, CheckRenameConnections("vm", "dev"))
+
+	require.NoError(t, AddConnection(&uri, "dev-root", "/ssh/dev", false))
+	assert.Error(t, CheckRenameConnections("vm", "dev"))
+	assert.Error(t, RenameConnections("vm", "dev", "/ssh/dev"))
+
+	cfg, err := config.ReadCustomConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "/ssh/vm", cfg.Engine.ServiceDestinations["vm-root"].Identity)
+}
@@ -0,0 +1,84 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// CpOptions describes a copy between the host and a machine
+type CpOptions struct {
+	HostPath  string
+	GuestPath string
+	// ToGuest copies HostPath to GuestPath, otherwise GuestPath is
+	// copied to HostPath
+	ToGuest        bool
+	Username       string
+	NoHostKeyCheck bool
+}
+
+// ParseCpArgs splits the source and destination of podman machine cp, one
+// of which names a path in a machine as NAME:PATH, into the machine name
+// and the options for its Cp method
+func ParseCpArgs(src, dest string) (string, CpOptions, error) {
+	windows := runtime.GOOS == "windows"
+	srcName, srcPath := splitCpPath(src, windows)
+	destName, destPath := splitCpPath(dest, windows)
+
+	switch {
+	case len(srcName) > 0 && len(destName) > 0:
+		return "", CpOptions{}, errors.New("copying between machines is not supported, one side must be a path on the host")
+	case len(srcName) == 0 && len(destName) == 0:
+		return "", CpOptions{}, errors.New("either the source or the destination must be a path in a machine, given as NAME:PATH")
+	case len(destName) > 0:
+		if len(srcPath) == 0 {
+			return "", CpOptions{}, errors.New("the host path must not be empty")
+		}
+		return destName, CpOptions{HostPath: srcPath, GuestPath: destPath, ToGuest: true}, nil
+	}
+	if len(destPath) == 0 {
+		return "", CpOptions{}, errors.New("the host path must not be empty")
+	}
+	return srcName, CpOptions{HostPath: destPath, GuestPath: srcPath}, nil
+}
+
+// splitCpPath returns the machine name and path of a NAME:PATH argument,
+// or an empty name for a path on the host. On Windows, a drive letter
+// followed by a colon starts a host path, not a machine name.
+func splitCpPath(arg string, windows bool) (string, string) {
+	name, path, found := strings.Cut(arg, ":")
+	if !found || len(name) == 0 {
+		return "", arg
+	}
+	if windows && len(name) == 1 && (len(path) == 0 || path[0] == '\\' || path[0] == '/') {
+		return "", arg
+	}
+	// Host paths such as ./a:b contain a separator before the colon
+	if strings.ContainsAny(name, `/\`) {
+		return "", arg
+	}
+	return name, path
+}
+
+// CpArgs returns the scp arguments that perform the copy described by opts
+// for a machine reachable on localhost at the given port. The host key is
+// handled the same way as for ssh.
+func CpArgs(identityPath string, port int, username string, opts CpOptions) []string {
+	args := append([]string{"-r"}, IdentityArgs(identityPath)...)
+	args = append(args, "-P", strconv.Itoa(port))
+	args = append(args, HostKeyArgs(identityPath, opts.NoHostKeyCheck)...)
+
+	// scp takes forward slashes on Windows too, and they keep a backslash
+	// from being read as an escape
+	hostPath := filepath.ToSlash(opts.HostPath)
+	guestPath := username + "@localhost:" + opts.GuestPath
+	if opts.ToGuest {
+		return append(args, hostPath, guestPath)
+	}
+	return append(args, guestPath, hostPath)
+}
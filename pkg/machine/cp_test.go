@@ -0,0 +1,64 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCpPath(t *testing.T) {
+	tests := []struct {
+		arg      string
+		windows  bool
+		wantName string
+		wantPath string
+	}{
+		{arg: "vm:/tmp/file", wantName: "vm", wantPath: "/tmp/file"},
+		{arg: "vm:", wantName: "vm"},
+		{arg: "./file", wantPath: "./file"},
+		{arg: "./a:b", wantPath: "./a:b"},
+		{arg: ":/tmp/file", wantPath: ":/tmp/file"},
+		{arg: `C:\Users\foo\file`, windows: true, wantPath: `C:\Users\foo\file`},
+		{arg: "C:/Users/foo/file", windows: true, wantPath: "C:/Users/foo/file"},
+		{arg: "c:/tmp/file", wantName: "c", wantPath: "/tmp/file"},
+		{arg: "podman-machine-default:file", windows: true, wantName: "podman-machine-default", wantPath: "file"},
+	}
+	for _, tt := range tests {
+		name, path := splitCpPath(tt.arg, tt.windows)
+		assert.Equal(t, tt.wantName, name, tt.arg)
+		assert.Equal(t, tt.wantPath, path, tt.arg)
+	}
+}
+
+func TestParseCpArgs(t *testing.T) {
+	name, opts, err := ParseCpArgs("./local", "vm:/tmp/remote")
+	assert.NoError(t, err)
+	assert.Equal(t, "vm", name)
+	assert.Equal(t, CpOptions{HostPath: "./local", GuestPath: "/tmp/remote", ToGuest: true}, opts)
+
+	name, opts, err = ParseCpArgs("vm:/tmp/remote", "./local")
+	assert.NoError(t, err)
+	assert.Equal(t, "vm", name)
+	assert.Equal(t, CpOptions{HostPath: "./local", GuestPath: "/tmp/remote"}, opts)
+
+	_, _, err = ParseCpArgs("vm:/a", "other:/b")
+	assert.Error(t, err)
+	_, _, err = ParseCpArgs("./a", "./b")
+	assert.Error(t, err)
+	_, _, err = ParseCpArgs("", "vm:/b")
+	assert.Error(t, err)
+}
+
+func TestCpArgs(t *testing.T) {
+	opts := CpOptions{HostPath: "local", GuestPath: "/tmp/remote", ToGuest: true, NoHostKeyCheck: true}
+	assert.Equal(t, []string{"-r", "-i", "/id", "-P", "2222",
+		"-o", "UserKnownHostsFile=/dev/null", "-o", "StrictHostKeyChecking=no",
+		"local", "user@localhost:/tmp/remote"}, CpArgs("/id", 2222, "user", opts))
+
+	opts.ToGuest = false
+	args := CpArgs("/id", 2222, "user", opts)
+	assert.Equal(t, []string{"user@localhost:/tmp/remote", "local"}, args[len(args)-2:])
+}
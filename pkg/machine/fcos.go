@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	url2 "net/url"
 	"os"
 	"path/filepath"
@@ -216,9 +215,9 @@ func GetFCOSDownload(vp VirtProvider, imageStream FCOSStream) (*FcosDownloadInfo
 	)
 
 	streamurl := getStreamURL(imageStream)
-	resp, err := http.Get(streamurl.String())
+	resp, err := HTTPClient().Get(streamurl.String())
 	if err != nil {
-		return nil, err
+		return nil, TimeoutError(err)
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
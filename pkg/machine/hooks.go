@@ -0,0 +1,73 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hooks are commands run after a machine changes state, configured by
+// setting the environment variable of the hook to the path of the command.
+// The [machine] table of containers.conf is parsed by containers/common,
+// which has no hook keys, so the environment is used instead.
+const (
+	PostStartHook = "PODMAN_MACHINE_POST_START_HOOK"
+	PostStopHook  = "PODMAN_MACHINE_POST_STOP_HOOK"
+)
+
+// hookTimeout bounds how long a hook may run before it is killed
+var hookTimeout = time.Minute
+
+// HookInfo describes the machine a hook is run for. It is passed to the
+// hook as its arguments, the name and state, and in the environment.
+type HookInfo struct {
+	Name          string
+	State         Status
+	SSHPort       int
+	ConnectionURI string
+}
+
+// HookConfigured reports whether a command is set for the hook
+func HookConfigured(hook string) bool {
+	return len(os.Getenv(hook)) > 0
+}
+
+// RunHook runs the command configured for the hook, if any. A failing
+// hook is logged but does not fail the operation it follows.
+func RunHook(hook string, info HookInfo) {
+	command := os.Getenv(hook)
+	if len(command) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, info.Name, info.State)
+	cmd.Env = append(os.Environ(),
+		"PODMAN_MACHINE_NAME="+info.Name,
+		"PODMAN_MACHINE_STATE="+info.State,
+		"PODMAN_MACHINE_SSH_PORT="+strconv.Itoa(info.SSHPort),
+		"PODMAN_MACHINE_CONNECTION_URI="+info.ConnectionURI,
+	)
+	// The output of start and stop is kept to podman's own, so
+	// scripts can still parse it
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	logrus.Debugf("Running %s hook: %s %s %s", hook, command, info.Name, info.State)
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logrus.Warnf("%s hook %s for machine %q did not finish within %s", hook, command, info.Name, hookTimeout)
+	case err != nil:
+		logrus.Warnf("%s hook %s for machine %q failed: %v", hook, command, info.Name, err)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build (amd64 || arm64) && !windows
+// +build amd64 arm64
+// +build !windows
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeHook(t *testing.T, script string) string {
+	hook := filepath.Join(t.TempDir(), "hook")
+	assert.NoError(t, os.WriteFile(hook, []byte("#!/bin/sh\n"+script), 0755))
+	return hook
+}
+
+func TestRunHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	hook := writeHook(t, `echo "$1 $2 $PODMAN_MACHINE_SSH_PORT $PODMAN_MACHINE_CONNECTION_URI" > `+out+"\n")
+
+	assert.False(t, HookConfigured(PostStartHook))
+	t.Setenv(PostStartHook, hook)
+	assert.True(t, HookConfigured(PostStartHook))
+
+	RunHook(PostStartHook, HookInfo{Name: "vm", State: Running, SSHPort: 2222, ConnectionURI: "ssh://user@localhost:2222/run/podman/podman.sock"})
+	b, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "vm running 2222 ssh://user@localhost:2222/run/podman/podman.sock\n", string(b))
+
+	// Hooks that are not configured are skipped
+	RunHook(PostStopHook, HookInfo{Name: "vm", State: Stopped})
+}
+
+func TestRunHookFailure(t *testing.T) {
+	oldTimeout := hookTimeout
+	defer func() { hookTimeout = oldTimeout }()
+	hookTimeout = 100 * time.Millisecond
+
+	// Neither a failing nor a hanging hook panics or blocks
+	t.Setenv(PostStopHook, writeHook(t, "exit 1\n"))
+	RunHook(PostStopHook, HookInfo{Name: "vm", State: Stopped})
+
+	t.Setenv(PostStopHook, writeHook(t, "exec sleep 10\n"))
+	start := time.Now()
+	RunHook(PostStopHook, HookInfo{Name: "vm", State: Stopped})
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	t.Setenv(PostStopHook, filepath.Join(t.TempDir(), "missing"))
+	RunHook(PostStopHook, HookInfo{Name: "vm", State: Stopped})
+}
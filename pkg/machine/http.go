@@ -0,0 +1,141 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// httpTimeoutEnv overrides the timeout applied to establishing connections
+	// and waiting for responses when downloading machine images
+	httpTimeoutEnv     = "PODMAN_MACHINE_HTTP_TIMEOUT"
+	defaultHTTPTimeout = 30 * time.Second
+)
+
+var (
+	httpClient     *http.Client
+	httpClientOnce sync.Once
+)
+
+// HTTPClient returns the client shared by all machine image related requests.
+// Dialing, the TLS handshake, waiting for response headers, and each wait for
+// more of the body are bounded by HTTPTimeout, so a stalled server can not
+// hang machine init indefinitely. The body as a whole is not bounded, since
+// images take a long time to transfer.
+func HTTPClient() *http.Client {
+	httpClientOnce.Do(func() {
+		httpClient = newHTTPClient(HTTPTimeout())
+	})
+	return httpClient
+}
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	// The clone keeps http.ProxyFromEnvironment, so downloads honor the
+	// same proxy variables as every other podman request
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Transport: &idleTimeoutTransport{base: transport, timeout: timeout}}
+}
+
+// idleTimeoutTransport fails reading a response body once no bytes
+// arrived for the timeout
+type idleTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	body := &idleTimeoutBody{body: resp.Body, cancel: cancel, timeout: t.timeout}
+	body.timer = time.AfterFunc(t.timeout, body.expire)
+	resp.Body = body
+	return resp, nil
+}
+
+// idleTimeoutBody cancels the request of body when the timer, which is
+// reset by every read returning data, expires
+type idleTimeoutBody struct {
+	body    io.ReadCloser
+	cancel  context.CancelFunc
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	expired bool
+}
+
+func (b *idleTimeoutBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.cancel()
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.mu.Lock()
+		expired := b.expired
+		b.mu.Unlock()
+		if expired {
+			return n, fmt.Errorf("no data received for %s: %w", b.timeout, context.DeadlineExceeded)
+		}
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	defer b.cancel()
+	return b.body.Close()
+}
+
+// HTTPTimeout returns the timeout for machine image related network
+// operations, which can be overridden with PODMAN_MACHINE_HTTP_TIMEOUT
+func HTTPTimeout() time.Duration {
+	value, found := os.LookupEnv(httpTimeoutEnv)
+	if !found || len(value) == 0 {
+		return defaultHTTPTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		logrus.Warnf("Ignoring invalid %s value %q, using %s", httpTimeoutEnv, value, defaultHTTPTimeout)
+		return defaultHTTPTimeout
+	}
+	return timeout
+}
+
+// TimeoutError annotates err with the configured timeout if it was caused
+// by a request timing out, and returns it unchanged otherwise
+func TimeoutError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("no response within %s (the limit can be raised with %s): %w", HTTPTimeout(), httpTimeoutEnv, err)
+	}
+	return err
+}
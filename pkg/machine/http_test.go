@@ -0,0 +1,117 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultHTTPTimeout},
+		{"5s", 5 * time.Second},
+		{"2m", 2 * time.Minute},
+		{"bogus", defaultHTTPTimeout},
+		{"-1s", defaultHTTPTimeout},
+	}
+	for _, tt := range tests {
+		t.Setenv(httpTimeoutEnv, tt.value)
+		assert.Equal(t, tt.want, HTTPTimeout(), tt.value)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Timeout: 10 * time.Millisecond}
+	_, err := client.Get(srv.URL)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(TimeoutError(err).Error(), httpTimeoutEnv))
+
+	assert.Nil(t, TimeoutError(nil))
+}
+
+func TestHTTPClientIdleTimeout(t *testing.T) {
+	stall := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			_, _ = io.WriteString(w, "data")
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+		if r.URL.Path == "/stall" {
+			<-stall
+		}
+	}))
+	defer srv.Close()
+	defer close(stall)
+
+	client := newHTTPClient(100 * time.Millisecond)
+
+	// A body that keeps arriving is not cut off
+	resp, err := client.Get(srv.URL + "/steady")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "datadatadata", string(body))
+	assert.NoError(t, resp.Body.Close())
+
+	resp, err = client.Get(srv.URL + "/stall")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	assert.Error(t, err)
+	assert.Equal(t, "datadatadata", string(body))
+	assert.Contains(t, TimeoutError(err).Error(), httpTimeoutEnv)
+}
+
+// proxyChildEnv marks the test binary run by TestHTTPClientUsesProxy.
+// net/http reads the proxy variables once per process, so they have to be
+// set before any request is made.
+const proxyChildEnv = "PODMAN_TEST_PROXY_CHILD"
+
+func TestHTTPClientUsesProxy(t *testing.T) {
+	if os.Getenv(proxyChildEnv) == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHTTPClientUsesProxy$")
+		cmd.Env = append(os.Environ(), proxyChildEnv+"=1")
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+		return
+	}
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("http_proxy", "")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+	resp, err := newHTTPClient(time.Second).Get("http://fedora.example.test/releases/latest")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "via proxy", string(body))
+	assert.Equal(t, "http://fedora.example.test/releases/latest", proxied)
+}
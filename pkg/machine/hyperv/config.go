@@ -51,6 +51,10 @@ func (v Virtualization) Format() machine.ImageFormat {
 	return v.format
 }
 
+func (v Virtualization) Import(_ string, _ string) (machine.VM, error) {
+	return nil, machine.ErrNotImplemented
+}
+
 func (v Virtualization) IsValidVMName(name string) (bool, error) {
 	// We check both the local filesystem and hyperv for the valid name
 	mm := HyperVMachine{Name: name}
@@ -97,6 +101,7 @@ func (v Virtualization) List(opts machine.ListOptions) ([]*machine.ListResponse,
 			Port:           mm.Port,
 			RemoteUsername: mm.RemoteUsername,
 			IdentityPath:   mm.IdentityPath,
+			Rootful:        mm.Rootful,
 		}
 		response = append(response, &mlr)
 	}
@@ -152,7 +157,7 @@ func (v Virtualization) NewMachine(opts machine.InitOptions) (machine.VM, error)
 		return nil, err
 	}
 	m.ImagePath = *imagePath
-	if err := machine.DownloadImage(g); err != nil {
+	if err := machine.DownloadImage(g, opts.Quiet); err != nil {
 		return nil, err
 	}
 
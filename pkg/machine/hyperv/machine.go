@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/url"
 	"os"
@@ -97,6 +98,7 @@ func (m *HyperVMachine) Init(opts machine.InitOptions) (bool, error) {
 
 	sshDir := filepath.Join(homedir.Get(), ".ssh")
 	m.IdentityPath = filepath.Join(sshDir, m.Name)
+	m.Rootful = opts.Rootful
 
 	if len(opts.IgnitionPath) < 1 {
 		uri := machine.SSHRemoteConnection.MakeSSHURL("localhost", fmt.Sprintf("/run/user/%d/podman/podman.sock", m.UID), strconv.Itoa(m.Port), m.RemoteUsername)
@@ -221,8 +223,10 @@ func (m *HyperVMachine) Inspect() (*machine.InspectInfo, error) {
 			DiskSize: 0,
 			Memory:   uint64(cfg.Hardware.Memory),
 		},
+		Rootful:   m.Rootful,
 		SSHConfig: m.SSHConfig,
 		State:     vm.State().String(),
+		VMType:    machine.HyperVVirt.String(),
 	}, nil
 }
 
@@ -282,6 +286,30 @@ func (m *HyperVMachine) Remove(_ string, opts machine.RemoveOptions) (string, fu
 	}, nil
 }
 
+func (m *HyperVMachine) Export(_ string, _ string) error {
+	return machine.ErrNotImplemented
+}
+
+func (m *HyperVMachine) Logs(_ string, _ io.Writer) error {
+	return machine.ErrNotImplemented
+}
+
+func (m *HyperVMachine) UpdateOS(_ string) (bool, error) {
+	return false, machine.ErrNotImplemented
+}
+
+func (m *HyperVMachine) Cp(_ string, _ machine.CpOptions) error {
+	return machine.ErrNotImplemented
+}
+
+func (m *HyperVMachine) PlanInit(_ machine.InitOptions) (*machine.InitPlan, error) {
+	return nil, machine.ErrNotImplemented
+}
+
+func (m *HyperVMachine) Rename(_ string, _ string) error {
+	return machine.ErrNotImplemented
+}
+
 func (m *HyperVMachine) Set(name string, opts machine.SetOptions) ([]error, error) {
 	var (
 		cpuChanged, memoryChanged bool
@@ -381,6 +409,10 @@ func (m *HyperVMachine) State(_ bool) (machine.Status, error) {
 }
 
 func (m *HyperVMachine) Stop(name string, opts machine.StopOptions) error {
+	// Hyper-V shuts the guest down on its own schedule
+	if opts.Timeout != nil && *opts.Timeout != machine.DefaultStopTimeout {
+		return errors.New("a stop timeout is not supported by Hyper-V machines")
+	}
 	vmm := hypervctl.NewVirtualMachineManager()
 	vm, err := vmm.GetMachine(m.Name)
 	if err != nil {
@@ -91,19 +91,9 @@ func NewIgnitionFile(ign DynamicIgnition, vmType VMType) error {
 
 	// Add or set the time zone for the machine
 	if len(ign.TimeZone) > 0 {
-		var (
-			err error
-			tz  string
-		)
-		// local means the same as the host
-		// look up where it is pointing to on the host
-		if ign.TimeZone == "local" {
-			tz, err = getLocalTimeZone()
-			if err != nil {
-				return err
-			}
-		} else {
-			tz = ign.TimeZone
+		tz, err := ResolveTimeZone(ign.TimeZone)
+		if err != nil {
+			return err
 		}
 		tzLink := Link{
 			Node: Node{
@@ -653,3 +643,12 @@ func getLinks(usrName string) []Link {
 func encodeDataURLPtr(contents string) *string {
 	return strToPtr(fmt.Sprintf("data:,%s", url.PathEscape(contents)))
 }
+
+// ResolveTimeZone returns the tz database name for a --timezone value,
+// where local means the same as the host
+func ResolveTimeZone(tz string) (string, error) {
+	if tz != "local" {
+		return tz, nil
+	}
+	return getLocalTimeZone()
+}
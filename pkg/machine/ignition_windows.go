@@ -3,6 +3,50 @@
 
 package machine
 
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// getLocalTimeZone returns the tz database name of the Windows time zone,
+// converted with the ICU library shipped with Windows 10 1903 and later
 func getLocalTimeZone() (string, error) {
-	return "", nil
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\TimeZoneInformation`, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+	windowsZone, _, err := key.GetStringValue("TimeZoneKeyName")
+	if err != nil {
+		return "", err
+	}
+
+	icu := syscall.NewLazyDLL("icu.dll")
+	procGetTimeZoneIDForWindowsID := icu.NewProc("ucal_getTimeZoneIDForWindowsID")
+	if err := procGetTimeZoneIDForWindowsID.Find(); err != nil {
+		return "", err
+	}
+	winID, err := syscall.UTF16FromString(windowsZone)
+	if err != nil {
+		return "", err
+	}
+	var (
+		id     [128]uint16
+		status int32
+	)
+	n, _, _ := procGetTimeZoneIDForWindowsID.Call(
+		uintptr(unsafe.Pointer(&winID[0])), uintptr(len(winID)-1), 0,
+		uintptr(unsafe.Pointer(&id[0])), uintptr(len(id)), uintptr(unsafe.Pointer(&status)))
+	// ICU reports failures with a positive status
+	if status > 0 {
+		return "", fmt.Errorf("could not convert time zone %q, ICU error %d", windowsZone, status)
+	}
+	if int32(n) <= 0 {
+		return "", errors.New("no tz database name for time zone " + windowsZone)
+	}
+	return syscall.UTF16ToString(id[:n]), nil
 }
@@ -26,6 +26,9 @@ func CreateSSHKeys(writeLocation string) (string, error) {
 	if err := generatekeys(writeLocation); err != nil {
 		return "", err
 	}
+	if err := EnsureKeyPermissions(writeLocation); err != nil {
+		return "", err
+	}
 	b, err := os.ReadFile(writeLocation + ".pub")
 	if err != nil {
 		return "", err
@@ -41,9 +44,12 @@ func CreateSSHKeysPrefix(dir string, file string, passThru bool, skipExisting bo
 		if err := generatekeysPrefix(dir, file, passThru, prefix...); err != nil {
 			return "", err
 		}
-	} else {
+	} else if passThru {
 		fmt.Println("Keys already exist, reusing")
 	}
+	if err := EnsureKeyPermissions(location); err != nil {
+		return "", err
+	}
 	b, err := os.ReadFile(filepath.Join(dir, file) + ".pub")
 	if err != nil {
 		return "", err
@@ -51,6 +57,25 @@ func CreateSSHKeysPrefix(dir string, file string, passThru bool, skipExisting bo
 	return strings.TrimSuffix(string(b), "\n"), nil
 }
 
+// EnsureKeyPermissions verifies that only the current user can access the
+// private key at path, and repairs the permissions if they are too open.
+// ssh refuses to use a private key that other users can read.
+func EnsureKeyPermissions(path string) error {
+	restricted, err := hasOwnerOnlyPermissions(path)
+	if err != nil {
+		return fmt.Errorf("could not check permissions of ssh key %q: %w", path, err)
+	}
+	if restricted {
+		return nil
+	}
+
+	logrus.Debugf("Restricting permissions of ssh key %q to the current user", path)
+	if err := setOwnerOnlyPermissions(path); err != nil {
+		return fmt.Errorf("could not restrict permissions of ssh key %q: %w", path, err)
+	}
+	return nil
+}
+
 // generatekeys creates an ed25519 set of keys
 func generatekeys(writeLocation string) error {
 	args := append(append([]string{}, sshCommand[1:]...), writeLocation)
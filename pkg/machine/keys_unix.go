@@ -0,0 +1,20 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package machine
+
+import (
+	"os"
+)
+
+func setOwnerOnlyPermissions(path string) error {
+	return os.Chmod(path, 0600)
+}
+
+func hasOwnerOnlyPermissions(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.Mode().Perm()&0077 == 0, nil
+}
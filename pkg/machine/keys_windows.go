@@ -0,0 +1,63 @@
+//go:build windows
+// +build windows
+
+package machine
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// setOwnerOnlyPermissions replaces the DACL of path with a single entry
+// granting the current user full control, and disables inheritance so that
+// permissive ACEs from the parent directory do not apply. This is the
+// equivalent of 0600 on Unix, and is required by OpenSSH for private keys.
+func setOwnerOnlyPermissions(path string) error {
+	sid, err := currentUserSID()
+	if err != nil {
+		return err
+	}
+
+	acl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{
+		{
+			AccessPermissions: windows.GENERIC_ALL,
+			AccessMode:        windows.GRANT_ACCESS,
+			Inheritance:       windows.NO_INHERITANCE,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  windows.TRUSTEE_IS_USER,
+				TrusteeValue: windows.TrusteeValueFromSID(sid),
+			},
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION,
+		nil, nil, acl, nil)
+}
+
+// hasOwnerOnlyPermissions reports whether the DACL of path consists
+// solely of a full control grant to the current user
+func hasOwnerOnlyPermissions(path string) (bool, error) {
+	sid, err := currentUserSID()
+	if err != nil {
+		return false, err
+	}
+
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return false, err
+	}
+
+	return sd.String() == "D:P(A;;FA;;;"+sid.String()+")", nil
+}
+
+func currentUserSID() (*windows.SID, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	return user.User.Sid, nil
+}
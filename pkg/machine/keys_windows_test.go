@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOwnerOnlyPermissions(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "machine")
+	require.NoError(t, os.WriteFile(key, []byte("key"), 0644))
+
+	restricted, err := hasOwnerOnlyPermissions(key)
+	require.NoError(t, err)
+	assert.False(t, restricted, "temp files should inherit the parent ACL")
+
+	require.NoError(t, setOwnerOnlyPermissions(key))
+	restricted, err = hasOwnerOnlyPermissions(key)
+	require.NoError(t, err)
+	assert.True(t, restricted)
+
+	// The owner must still be able to read the key
+	b, err := os.ReadFile(key)
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(b))
+}
@@ -0,0 +1,82 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrOperationInProgress is returned when another process is operating on
+// the same machine
+var ErrOperationInProgress = errors.New("an operation on the machine is already in progress")
+
+// errLocked is returned by tryLockFile when another process holds the lock
+var errLocked = errors.New("file is locked")
+
+// MachineLock is an exclusive lock on a machine name, held across
+// processes for the duration of an operation that creates, starts, stops,
+// changes or removes it
+type MachineLock struct {
+	file *os.File
+}
+
+// LockMachine locks the named machine without waiting, returning an error
+// wrapping ErrOperationInProgress when another process holds the lock. The
+// lock is released by Unlock, or when the process exits.
+func LockMachine(vmType VMType, name string) (*MachineLock, error) {
+	confDir, err := GetConfDir(vmType)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(confDir, name+".lock")
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open machine lock %s: %w", path, err)
+	}
+	if err := tryLockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("%s: %w", name, ErrOperationInProgress)
+		}
+		return nil, fmt.Errorf("could not lock %s: %w", path, err)
+	}
+	return &MachineLock{file: file}, nil
+}
+
+// Unlock releases the lock. It may be called more than once, and on a nil
+// lock, so a lock released early can still be released by a deferred call.
+func (l *MachineLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Remove releases the lock and deletes its file, for a machine name that is
+// no longer in use, such as the old name of a renamed machine
+func (l *MachineLock) Remove() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	path := l.file.Name()
+	// Removing the file while it is still locked keeps another process from
+	// locking it in between. Windows does not remove open files, so retry
+	// there once the lock is released.
+	removeErr := os.Remove(path)
+	if err := l.Unlock(); err != nil {
+		return err
+	}
+	if removeErr != nil {
+		removeErr = os.Remove(path)
+	}
+	if removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+		return removeErr
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockMachine(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	lock, err := LockMachine(QemuVirt, "test")
+	assert.NoError(t, err)
+
+	// A second descriptor conflicts just like another process would
+	_, err = LockMachine(QemuVirt, "test")
+	assert.ErrorIs(t, err, ErrOperationInProgress)
+
+	other, err := LockMachine(QemuVirt, "other")
+	assert.NoError(t, err)
+	assert.NoError(t, other.Unlock())
+
+	assert.NoError(t, lock.Unlock())
+	lock, err = LockMachine(QemuVirt, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Unlock())
+}
+
+func TestLockMachineRace(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Operations such as a start and a remove racing on one machine: only
+	// one gets the lock, the others fail rather than wait
+	const racers = 8
+	var (
+		wg      sync.WaitGroup
+		ready   sync.WaitGroup
+		release = make(chan struct{})
+		errs    = make(chan error, racers)
+	)
+	ready.Add(racers)
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := LockMachine(QemuVirt, "test")
+			ready.Done()
+			errs <- err
+			if err == nil {
+				<-release
+				assert.NoError(t, lock.Unlock())
+			}
+		}()
+	}
+	ready.Wait()
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	locked := 0
+	for err := range errs {
+		if err == nil {
+			locked++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrOperationInProgress)
+	}
+	assert.Equal(t, 1, locked)
+}
+
+func TestMachineLockUnlockTwice(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	lock, err := LockMachine(QemuVirt, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Unlock())
+	assert.NoError(t, lock.Unlock())
+
+	var none *MachineLock
+	assert.NoError(t, none.Unlock())
+}
+
+func TestMachineLockRemove(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	lock, err := LockMachine(QemuVirt, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Remove())
+	assert.NoError(t, lock.Unlock())
+
+	confDir, err := GetConfDir(QemuVirt)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(confDir, "test.lock"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	lock, err = LockMachine(QemuVirt, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Unlock())
+}
@@ -0,0 +1,20 @@
+//go:build (amd64 || arm64) && (darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+// +build amd64 arm64
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package machine
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func tryLockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return errLocked
+	}
+	return err
+}
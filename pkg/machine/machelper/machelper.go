@@ -0,0 +1,148 @@
+// Package machelper reports on podman-mac-helper, the service that links
+// /var/run/docker.sock to the API socket of a machine on macOS, so tools
+// and podman itself can tell whether the link is the helper's and where it
+// leads.
+package machelper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DockerSock is the socket the helper links to a machine
+const DockerSock = "/var/run/docker.sock"
+
+// The helper links docker.sock to one of the user global sockets, which in
+// turn link to the API socket of the running machine. The rootful socket is
+// only linked while a rootful machine is running.
+const (
+	UserSocket        = "podman.sock"
+	RootfulUserSocket = "podman-root.sock"
+)
+
+// launchDaemonsDir holds the launchd plists of system services
+const launchDaemonsDir = "/Library/LaunchDaemons"
+
+// MachineDirEnv relocates the machines, as it does for podman machine. Their
+// data, including the user global sockets, is kept in its data
+// subdirectory.
+const MachineDirEnv = "CONTAINERS_MACHINE_DIR"
+
+// MachineDir returns the directory holding the user global sockets and the
+// machines of the user with homeDir. Podman itself should use the data
+// directory of pkg/machine, which also follows XDG_DATA_HOME.
+func MachineDir(homeDir string) string {
+	if dir := os.Getenv(MachineDirEnv); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "data")
+	}
+	return filepath.Join(homeDir, ".local", "share", "containers", "podman", "machine")
+}
+
+// IsHelperLink reports whether link, what docker.sock links to, was created
+// by the helper: it points at target, the socket the plist names, or into
+// machineDir. Links of other docker installations, such as Docker Desktop,
+// point elsewhere.
+func IsHelperLink(link, target, machineDir string) bool {
+	if len(link) == 0 {
+		return false
+	}
+	link = filepath.Clean(link)
+	return (len(target) > 0 && link == target) || strings.HasPrefix(link, machineDir+string(filepath.Separator))
+}
+
+// Label returns the launchd label of the helper installed for userName
+func Label(userName string) string {
+	return fmt.Sprintf("com.github.containers.podman.helper-%s", userName)
+}
+
+// PlistFile returns the path of the launchd plist of the helper installed
+// for userName
+func PlistFile(userName string) string {
+	return filepath.Join(launchDaemonsDir, Label(userName)+".plist")
+}
+
+var plistTargetRegex = regexp.MustCompile(`<string>service</string>\s*<string>([^<]+)</string>`)
+
+// PlistTarget returns the socket the service installed with the plist
+// links docker.sock to, or an empty string if it can not be determined
+func PlistTarget(fileName string) string {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return ""
+	}
+	match := plistTargetRegex.FindSubmatch(content)
+	if match == nil {
+		return ""
+	}
+	return string(match[1])
+}
+
+// Status describes the helper of a user and the docker.sock link
+type Status struct {
+	// Installed is set when the plist of the helper is installed
+	Installed bool
+	PlistFile string
+	// PlistTarget is the socket the helper links docker.sock to when it
+	// starts, normally the user global socket of the podman machines
+	PlistTarget string
+	// MachineDir is the directory of the podman machines of the user
+	MachineDir string
+	// Link is what docker.sock links to, empty if it is not a link
+	Link string
+	// Target is the socket docker.sock resolves to after following all
+	// links, such as the API socket of a machine. It is empty if
+	// docker.sock does not exist or a link along the way is dangling.
+	Target string
+}
+
+// Managed reports whether docker.sock is a link of the installed helper.
+// The helper links to PlistTarget, which retarget can replace with a
+// socket in MachineDir.
+func (s *Status) Managed() bool {
+	return s.Installed && IsHelperLink(s.Link, s.PlistTarget, s.MachineDir)
+}
+
+// GetStatus returns whether the helper is installed for userName, whose
+// machines are in machineDir, and where docker.sock leads
+func GetStatus(userName, machineDir string) (*Status, error) {
+	return getStatus(PlistFile(userName), DockerSock, machineDir)
+}
+
+func getStatus(plistFile, dockerSock, machineDir string) (*Status, error) {
+	s := Status{PlistFile: plistFile, MachineDir: machineDir}
+
+	info, err := os.Stat(plistFile)
+	switch {
+	case err == nil:
+		s.Installed = info.Mode().IsRegular()
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("could not check helper plist: %w", err)
+	}
+	if s.Installed {
+		s.PlistTarget = PlistTarget(plistFile)
+	}
+
+	info, err = os.Lstat(dockerSock)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &s, nil
+		}
+		return nil, fmt.Errorf("could not check %s: %w", dockerSock, err)
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		s.Target = dockerSock
+		return &s, nil
+	}
+	if s.Link, err = os.Readlink(dockerSock); err != nil {
+		return nil, fmt.Errorf("could not read link %s: %w", dockerSock, err)
+	}
+	if target, err := filepath.EvalSymlinks(dockerSock); err == nil {
+		s.Target = target
+	}
+	return &s, nil
+}
@@ -0,0 +1,94 @@
+package machelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlist = `<array>
+		<string>/usr/local/podman/helper/me/podman-mac-helper</string>
+		<string>service</string>
+		<string>/Users/me/.local/share/containers/podman/machine/podman.sock</string>
+	</array>`
+
+func TestGetStatus(t *testing.T) {
+	dir := t.TempDir()
+	plist := filepath.Join(dir, "helper.plist")
+	dockerSock := filepath.Join(dir, "docker.sock")
+	userSock := filepath.Join(dir, "podman.sock")
+	machineSock := filepath.Join(dir, "machine.sock")
+
+	s, err := getStatus(plist, dockerSock, dir)
+	require.NoError(t, err)
+	assert.False(t, s.Installed)
+	assert.False(t, s.Managed())
+	assert.Empty(t, s.Target)
+
+	require.NoError(t, os.WriteFile(plist, []byte(testPlist), 0644))
+	require.NoError(t, os.WriteFile(machineSock, nil, 0644))
+	require.NoError(t, os.Symlink(machineSock, userSock))
+	require.NoError(t, os.Symlink(userSock, dockerSock))
+
+	s, err = getStatus(plist, dockerSock, dir)
+	require.NoError(t, err)
+	assert.True(t, s.Installed)
+	assert.True(t, s.Managed())
+	assert.Equal(t, "/Users/me/.local/share/containers/podman/machine/podman.sock", s.PlistTarget)
+	assert.Equal(t, userSock, s.Link)
+	want, err := filepath.EvalSymlinks(machineSock)
+	require.NoError(t, err)
+	assert.Equal(t, want, s.Target)
+
+	// A dangling link is still the helper's, but leads nowhere
+	require.NoError(t, os.Remove(machineSock))
+	s, err = getStatus(plist, dockerSock, dir)
+	require.NoError(t, err)
+	assert.True(t, s.Managed())
+	assert.Empty(t, s.Target)
+
+	// Docker Desktop links docker.sock to a socket of its own
+	desktopSock := filepath.Join(t.TempDir(), "docker.sock")
+	require.NoError(t, os.WriteFile(desktopSock, nil, 0644))
+	require.NoError(t, os.Remove(dockerSock))
+	require.NoError(t, os.Symlink(desktopSock, dockerSock))
+	s, err = getStatus(plist, dockerSock, dir)
+	require.NoError(t, err)
+	assert.False(t, s.Managed())
+	assert.Equal(t, desktopSock, s.Link)
+
+	// A socket of another docker installation is not managed by the helper
+	require.NoError(t, os.Remove(dockerSock))
+	require.NoError(t, os.WriteFile(dockerSock, nil, 0644))
+	s, err = getStatus(plist, dockerSock, dir)
+	require.NoError(t, err)
+	assert.False(t, s.Managed())
+	assert.Equal(t, dockerSock, s.Target)
+}
+
+func TestIsHelperLink(t *testing.T) {
+	machineDir := "/Users/me/.local/share/containers/podman/machine"
+	target := "/opt/podman/podman.sock"
+
+	assert.True(t, IsHelperLink(target, target, machineDir))
+	assert.True(t, IsHelperLink(machineDir+"/podman.sock", target, machineDir))
+	assert.True(t, IsHelperLink(machineDir+"/qemu/podman-machine-default_api.sock", "", machineDir))
+	assert.False(t, IsHelperLink("/Users/me/.docker/run/docker.sock", target, machineDir))
+	assert.False(t, IsHelperLink(machineDir+"-other/podman.sock", target, machineDir))
+	assert.False(t, IsHelperLink("", "", machineDir))
+}
+
+func TestMachineDir(t *testing.T) {
+	t.Setenv(MachineDirEnv, "")
+	assert.Equal(t, "/Users/me/.local/share/containers/podman/machine", MachineDir("/Users/me"))
+
+	t.Setenv(MachineDirEnv, "/Volumes/vms")
+	assert.Equal(t, "/Volumes/vms/data", MachineDir("/Users/me"))
+
+	// podman machine rejects relative paths, so they are ignored here
+	t.Setenv(MachineDirEnv, "vms")
+	assert.Equal(t, "/Users/me/.local/share/containers/podman/machine", MachineDir("/Users/me"))
+}
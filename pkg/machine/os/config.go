@@ -7,9 +7,14 @@ package os
 type Manager interface {
 	// Apply machine OS changes from an OCI image.
 	Apply(image string, opts ApplyOptions) error
+	// Update the packages of the machine OS.
+	Update(opts UpdateOptions) error
 }
 
 // ApplyOptions are the options for applying an image into a Podman machine VM
 type ApplyOptions struct {
 	Image string
 }
+
+// UpdateOptions are the options for updating the OS of a Podman machine VM
+type UpdateOptions struct{}
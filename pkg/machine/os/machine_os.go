@@ -38,3 +38,27 @@ func (m *MachineOS) Apply(image string, opts ApplyOptions) error {
 	}
 	return nil
 }
+
+// Update updates the OS of the machine from outside the machine, and
+// restarts it when that is recommended and requested.
+func (m *MachineOS) Update(opts UpdateOptions) error {
+	restart, err := m.VM.UpdateOS(m.VMName)
+	if err != nil {
+		return err
+	}
+	if !restart {
+		return nil
+	}
+	if !m.Restart {
+		fmt.Printf("Restart machine %q to apply the updates\n", m.VMName)
+		return nil
+	}
+	if err := m.VM.Stop(m.VMName, machine.StopOptions{}); err != nil {
+		return err
+	}
+	if err := m.VM.Start(m.VMName, machine.StartOptions{NoInfo: true}); err != nil {
+		return err
+	}
+	fmt.Printf("Machine %q restarted successfully\n", m.VMName)
+	return nil
+}
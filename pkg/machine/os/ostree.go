@@ -105,6 +105,15 @@ func (dist *OSTree) Apply(image string, opts ApplyOptions) error {
 	return cmd.Run()
 }
 
+// Update upgrades the OS of the machine to the latest deployment from
+// inside the machine
+func (dist *OSTree) Update(opts UpdateOptions) error {
+	cmd := exec.Command("sudo", "rpm-ostree", "upgrade")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 // pathSafeString creates a path-safe name for our tmpdirs
 func pathSafeString(str string) string {
 	alphanumOnly := regexp.MustCompile(`[^a-zA-Z0-9]+`)
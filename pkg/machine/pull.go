@@ -15,6 +15,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -116,14 +117,17 @@ func (g GenericDownload) CleanCache() error {
 	return nil
 }
 
-func DownloadImage(d DistributionDownload) error {
+// DownloadImage fetches the image described by d unless a usable copy is
+// already cached, and decompresses it. Download progress is written to
+// stdout unless quiet is set.
+func DownloadImage(d DistributionDownload, quiet bool) error {
 	// check if the latest image is already present
 	ok, err := d.HasUsableCache()
 	if err != nil {
 		return err
 	}
 	if !ok {
-		if err := DownloadVMImage(d.Get().URL, d.Get().ImageName, d.Get().LocalPath); err != nil {
+		if err := DownloadVMImage(d.Get().URL, d.Get().ImageName, d.Get().LocalPath, d.Get().Size, quiet); err != nil {
 			return err
 		}
 		// Clean out old cached images, since we didn't find needed image in cache
@@ -137,63 +141,187 @@ func DownloadImage(d DistributionDownload) error {
 }
 
 // DownloadVMImage downloads a VM image from url to given path
-// with download status
-func DownloadVMImage(downloadURL *url2.URL, imageName string, localImagePath string) error {
-	out, err := os.Create(localImagePath)
+// with download status. The image is first written to a ".part"
+// file next to the destination; if one is left over from an earlier,
+// interrupted attempt, the download is resumed with a range request.
+// expectedSize is used for progress reporting when the server does not
+// announce the length of the image, pass 0 if it is not known. When it is
+// known and PODMAN_MACHINE_DOWNLOAD_CONNECTIONS is above 1, the image is
+// fetched with that many concurrent range requests.
+func DownloadVMImage(downloadURL *url2.URL, imageName string, localImagePath string, expectedSize int64, quiet bool) error {
+	partialPath := localImagePath + ".part"
+
+	var offset int64
+	if info, err := os.Stat(partialPath); err == nil {
+		offset = info.Size()
+	}
+
+	// Splitting the image needs its size up front, and an interrupted
+	// download is better resumed over a single stream
+	if connections := DownloadConnections(); connections > 1 && expectedSize > 0 && offset == 0 {
+		err := downloadChunked(downloadURL, imageName, partialPath, expectedSize, connections, quiet)
+		switch {
+		case err == nil:
+			return os.Rename(partialPath, localImagePath)
+		case errors.Is(err, errRangeNotSupported):
+			logrus.Debugf("Server does not support range requests for %s, downloading with a single connection", downloadURL)
+		default:
+			return err
+		}
+	}
+
+	resp, err := requestImage(downloadURL, offset)
 	if err != nil {
-		return err
+		return fmt.Errorf("downloading VM image %s: %w", downloadURL, TimeoutError(err))
 	}
 	defer func() {
-		if err := out.Close(); err != nil {
+		if err := resp.Body.Close(); err != nil {
 			logrus.Error(err)
 		}
 	}()
 
-	resp, err := http.Get(downloadURL.String())
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, _, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			return fmt.Errorf("downloading VM image %s: unexpected content range %q", downloadURL, resp.Header.Get("Content-Range"))
+		}
+		logrus.Debugf("Resuming download of %s at offset %d", downloadURL, offset)
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		if offset > 0 {
+			logrus.Debugf("Server does not support resuming %s, restarting download", downloadURL)
+		}
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not a prefix of the remote image (e.g. the
+		// image changed upstream), start over with a clean file
+		logrus.Debugf("Discarding unusable partial download %s", partialPath)
+		if err := os.Remove(partialPath); err != nil {
+			return err
+		}
+		return DownloadVMImage(downloadURL, imageName, localImagePath, expectedSize, quiet)
+	default:
+		return fmt.Errorf("downloading VM image %s: %s", downloadURL, resp.Status)
+	}
+
+	out, err := os.OpenFile(partialPath, flags, 0644)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if err := resp.Body.Close(); err != nil {
+		if err := out.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
 			logrus.Error(err)
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("downloading VM image %s: %s", downloadURL, resp.Status)
-	}
 	size := resp.ContentLength
+	if size >= 0 {
+		size += offset
+	}
+	// Without a known total fall back to the size the distribution
+	// advertised, both for the bar and for checking the download
+	total := size
+	if total < 0 && expectedSize > 0 {
+		total = expectedSize
+	}
+	p, bar := newDownloadBar(imageName, total, quiet)
+	bar.SetCurrent(offset)
+
+	proxyReader := bar.ProxyReader(throttle(resp.Body, newDownloadLimiter()))
+	defer func() {
+		if err := proxyReader.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	if _, err := io.Copy(out, proxyReader); err != nil {
+		return fmt.Errorf("downloading VM image %s: %w", downloadURL, TimeoutError(err))
+	}
+	info, err := out.Stat()
+	if err != nil {
+		return err
+	}
+	if total >= 0 && info.Size() != total {
+		// The bar only completes on its own once it reaches the total
+		bar.Abort(false)
+		p.Wait()
+		return fmt.Errorf("downloading VM image %s: incomplete download, got %d of %d bytes", downloadURL, info.Size(), total)
+	}
+	// Complete the bar even if the total was unknown
+	bar.SetTotal(-1, true)
+
+	p.Wait()
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(partialPath, localImagePath)
+}
+
+// newDownloadBar creates the progress bar for downloading imageName,
+// writing to stdout unless quiet is set
+func newDownloadBar(imageName string, total int64, quiet bool) (*mpb.Progress, *mpb.Bar) {
 	prefix := "Downloading VM image: " + imageName
 	onComplete := prefix + ": done"
 
+	output := io.Writer(os.Stdout)
+	if quiet {
+		output = io.Discard
+	}
 	p := mpb.New(
+		mpb.WithOutput(output),
 		mpb.WithWidth(60),
 		mpb.WithRefreshRate(180*time.Millisecond),
 	)
 
-	bar := p.AddBar(size,
+	bar := p.AddBar(total,
 		mpb.BarFillerClearOnComplete(),
 		mpb.PrependDecorators(
 			decor.OnComplete(decor.Name(prefix), onComplete),
 		),
 		mpb.AppendDecorators(
 			decor.OnComplete(decor.CountersKibiByte("%.1f / %.1f"), ""),
+			decor.OnComplete(decor.Percentage(decor.WCSyncSpace), ""),
+			decor.OnComplete(decor.EwmaSpeed(decor.UnitKiB, "% .1f", 30, decor.WCSyncSpace), ""),
 		),
 	)
+	return p, bar
+}
 
-	proxyReader := bar.ProxyReader(resp.Body)
-	defer func() {
-		if err := proxyReader.Close(); err != nil {
-			logrus.Error(err)
-		}
-	}()
-
-	if _, err := io.Copy(out, proxyReader); err != nil {
-		return err
+// requestImage issues a GET request for the image, asking for
+// the content starting at offset when offset is non-zero
+func requestImage(downloadURL *url2.URL, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, downloadURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
+	return HTTPClient().Do(req)
+}
 
-	p.Wait()
-	return nil
+// parseContentRange parses the first byte position and the total size
+// out of a "bytes start-end/total" Content-Range header value. The total
+// is -1 if the server does not know it.
+func parseContentRange(contentRange string) (int64, int64, error) {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return -1, -1, err
+	}
+	if total == "*" {
+		return start, -1, nil
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1, -1, err
+	}
+	return start, size, nil
 }
 
 func Decompress(localPath, uncompressedPath string) error {
@@ -0,0 +1,174 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	url2 "net/url"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// downloadConnectionsEnv sets the number of concurrent range requests
+	// used to download a machine image
+	downloadConnectionsEnv = "PODMAN_MACHINE_DOWNLOAD_CONNECTIONS"
+	maxDownloadConnections = 16
+	// minChunkSize keeps small images from being split into many requests
+	minChunkSize = 1024 * 1024
+)
+
+var errRangeNotSupported = errors.New("server does not support range requests")
+
+// DownloadConnections returns the number of concurrent connections used to
+// download a machine image, as set with PODMAN_MACHINE_DOWNLOAD_CONNECTIONS.
+// The default of 1 downloads the image in a single stream.
+func DownloadConnections() int {
+	value, found := os.LookupEnv(downloadConnectionsEnv)
+	if !found || len(value) == 0 {
+		return 1
+	}
+	connections, err := strconv.Atoi(value)
+	if err != nil || connections < 1 {
+		logrus.Warnf("Ignoring invalid %s value %q, downloading with a single connection", downloadConnectionsEnv, value)
+		return 1
+	}
+	if connections > maxDownloadConnections {
+		logrus.Warnf("Limiting %s to %d connections", downloadConnectionsEnv, maxDownloadConnections)
+		return maxDownloadConnections
+	}
+	return connections
+}
+
+// downloadChunked fetches the image of the given size into partialPath by
+// splitting it into ranges requested over concurrent connections. It returns
+// errRangeNotSupported, leaving nothing behind, if the server does not honor
+// the ranges, so the caller can fall back to a single stream.
+func downloadChunked(downloadURL *url2.URL, imageName, partialPath string, size int64, connections int, quiet bool) (err error) {
+	out, err := os.OpenFile(partialPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			if removeErr := os.Remove(partialPath); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+				logrus.Error(removeErr)
+			}
+		}
+	}()
+
+	p, bar := newDownloadBar(imageName, size, quiet)
+
+	chunks := int64(connections)
+	if size/chunks < minChunkSize {
+		chunks = size / minChunkSize
+		if chunks < 1 {
+			chunks = 1
+		}
+	}
+	chunkSize := size / chunks
+	// The connections share one limiter, so the rate applies to the
+	// download as a whole
+	limiter := newDownloadLimiter()
+	proxy := func(r io.Reader) io.ReadCloser {
+		return bar.ProxyReader(throttle(r, limiter))
+	}
+	group, ctx := errgroup.WithContext(context.Background())
+	for i := int64(0); i < chunks; i++ {
+		start, end := i*chunkSize, (i+1)*chunkSize-1
+		if i == chunks-1 {
+			// The last chunk picks up the remainder
+			end = size - 1
+		}
+		group.Go(func() error {
+			return downloadRange(ctx, downloadURL, out, start, end, size, proxy)
+		})
+	}
+	err = group.Wait()
+	if err != nil {
+		bar.Abort(false)
+	} else {
+		bar.SetTotal(-1, true)
+	}
+	p.Wait()
+	if err != nil {
+		return err
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("downloading VM image %s: reassembled %d of %d bytes", downloadURL, info.Size(), size)
+	}
+	return nil
+}
+
+// downloadRange writes the bytes start through end (inclusive) of the
+// image to the same offsets in out. The ranges are computed from the
+// expected size, so it fails if the server reports a different one.
+func downloadRange(ctx context.Context, downloadURL *url2.URL, out io.WriterAt, start, end, size int64, proxy func(io.Reader) io.ReadCloser) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("downloading VM image %s: %w", downloadURL, TimeoutError(err))
+	}
+	body := proxy(resp.Body)
+	defer func() {
+		if err := body.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return errRangeNotSupported
+	default:
+		return fmt.Errorf("downloading VM image %s: %s", downloadURL, resp.Status)
+	}
+	rangeStart, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil || rangeStart != start {
+		return fmt.Errorf("downloading VM image %s: unexpected content range %q", downloadURL, resp.Header.Get("Content-Range"))
+	}
+	if total >= 0 && total != size {
+		return fmt.Errorf("downloading VM image %s: image is %d bytes, expected %d", downloadURL, total, size)
+	}
+
+	n, err := io.Copy(&offsetWriter{w: out, offset: start}, io.LimitReader(body, end-start+1))
+	if err != nil {
+		return fmt.Errorf("downloading VM image %s: %w", downloadURL, TimeoutError(err))
+	}
+	if n != end-start+1 {
+		return fmt.Errorf("downloading VM image %s: short read for range %d-%d", downloadURL, start, end)
+	}
+	return nil
+}
+
+// offsetWriter writes sequentially to w starting at offset
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
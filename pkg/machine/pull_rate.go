@@ -0,0 +1,109 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/sirupsen/logrus"
+)
+
+// downloadRateEnv limits the bandwidth used to download a machine image
+const downloadRateEnv = "PODMAN_MACHINE_DOWNLOAD_RATE"
+
+// DownloadRate returns the limit in bytes per second for downloading a
+// machine image, as set with PODMAN_MACHINE_DOWNLOAD_RATE in a form such as
+// "2MB". The default of 0 does not limit the download.
+func DownloadRate() int64 {
+	value, found := os.LookupEnv(downloadRateEnv)
+	if !found || len(value) == 0 {
+		return 0
+	}
+	rate, err := units.FromHumanSize(value)
+	if err != nil || rate < 0 {
+		logrus.Warnf("Ignoring invalid %s value %q, downloading at full speed", downloadRateEnv, value)
+		return 0
+	}
+	return rate
+}
+
+// rateLimiter paces the reads of all readers sharing it, so that together
+// they stay within the rate
+type rateLimiter struct {
+	rate int64
+	mu   sync.Mutex
+	// next is when the bytes read so far are paid for
+	next time.Time
+}
+
+// newDownloadLimiter returns the limiter for the configured download rate,
+// or nil if the download is not limited
+func newDownloadLimiter() *rateLimiter {
+	rate := DownloadRate()
+	if rate == 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate}
+}
+
+// wait blocks until n more bytes fit within the rate
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
+
+// maxRead keeps single reads to a tenth of a second worth of data, so the
+// transfer and its progress bar advance smoothly
+func (l *rateLimiter) maxRead() int {
+	if n := l.rate / 10; n > 1 {
+		return int(n)
+	}
+	return 1
+}
+
+type throttledReader struct {
+	r io.Reader
+	l *rateLimiter
+}
+
+// throttle limits reads from r with the limiter. Closing the returned
+// reader closes r. A nil limiter leaves the reads unlimited.
+func throttle(r io.Reader, l *rateLimiter) io.ReadCloser {
+	if l == nil {
+		if rc, ok := r.(io.ReadCloser); ok {
+			return rc
+		}
+		return io.NopCloser(r)
+	}
+	return &throttledReader{r: r, l: l}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if max := t.l.maxRead(); len(p) > max {
+		p = p[:max]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.l.wait(n)
+	}
+	return n, err
+}
+
+func (t *throttledReader) Close() error {
+	if c, ok := t.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
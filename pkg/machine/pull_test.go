@@ -0,0 +1,297 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	url2 "net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadVMImageResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+
+	tests := []struct {
+		name          string
+		partial       []byte
+		rangeSupport  bool
+		wantRangeSeen bool
+	}{
+		{
+			name:         "no partial file",
+			rangeSupport: true,
+		},
+		{
+			name:          "resume partial file",
+			partial:       content[:4000],
+			rangeSupport:  true,
+			wantRangeSeen: true,
+		},
+		{
+			name:          "server without range support restarts",
+			partial:       []byte("garbage"),
+			rangeSupport:  false,
+			wantRangeSeen: true,
+		},
+		{
+			name:          "partial file larger than image restarts",
+			partial:       append(append([]byte{}, content...), content...),
+			rangeSupport:  true,
+			wantRangeSeen: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			rangeSeen := false
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "" {
+					rangeSeen = true
+				}
+				if !tt.rangeSupport {
+					r.Header.Del("Range")
+				}
+				http.ServeContent(w, r, "image.xz", time.Time{}, bytes.NewReader(content))
+			}))
+			defer srv.Close()
+
+			dest := filepath.Join(t.TempDir(), "image.xz")
+			if tt.partial != nil {
+				require.NoError(t, os.WriteFile(dest+".part", tt.partial, 0644))
+			}
+
+			u, err := url2.Parse(srv.URL + "/image.xz")
+			require.NoError(t, err)
+			require.NoError(t, DownloadVMImage(u, "image.xz", dest, 0, true))
+
+			got, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+			assert.Equal(t, tt.wantRangeSeen, rangeSeen)
+			_, err = os.Stat(dest + ".part")
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestDownloadVMImageUnknownLength(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing before writing the body forces a chunked response
+		// without a Content-Length
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(content)
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "image.xz")
+	u, err := url2.Parse(srv.URL + "/image.xz")
+	require.NoError(t, err)
+	require.NoError(t, DownloadVMImage(u, "image.xz", dest, int64(len(content)), true))
+
+	got, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// The advertised size catches a truncated body
+	dest = filepath.Join(t.TempDir(), "image.xz")
+	err = DownloadVMImage(u, "image.xz", dest, int64(len(content))+1, true)
+	assert.ErrorContains(t, err, "incomplete download")
+	_, err = os.Stat(dest)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadVMImageChunked(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 512*1024)
+
+	tests := []struct {
+		name         string
+		rangeSupport bool
+		wantFull     int
+	}{
+		{
+			name:         "ranges are reassembled",
+			rangeSupport: true,
+		},
+		{
+			name:         "server without range support falls back",
+			rangeSupport: false,
+			wantFull:     1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(downloadConnectionsEnv, "4")
+
+			var mu sync.Mutex
+			ranges, full := 0, 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				if r.Header.Get("Range") != "" {
+					ranges++
+				} else {
+					full++
+				}
+				mu.Unlock()
+				if !tt.rangeSupport {
+					r.Header.Del("Range")
+				}
+				http.ServeContent(w, r, "image.xz", time.Time{}, bytes.NewReader(content))
+			}))
+			defer srv.Close()
+
+			dest := filepath.Join(t.TempDir(), "image.xz")
+			u, err := url2.Parse(srv.URL + "/image.xz")
+			require.NoError(t, err)
+			require.NoError(t, DownloadVMImage(u, "image.xz", dest, int64(len(content)), true))
+
+			got, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+			assert.Equal(t, tt.wantFull, full)
+			if tt.rangeSupport {
+				assert.Equal(t, 4, ranges)
+			}
+			_, err = os.Stat(dest + ".part")
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestDownloadVMImageChunkedSizeMismatch(t *testing.T) {
+	t.Setenv(downloadConnectionsEnv, "4")
+
+	content := bytes.Repeat([]byte("0123456789"), 512*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "image.xz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "image.xz")
+	u, err := url2.Parse(srv.URL + "/image.xz")
+	require.NoError(t, err)
+	// A stale size must not produce a truncated image
+	err = DownloadVMImage(u, "image.xz", dest, int64(len(content)/2), true)
+	assert.ErrorContains(t, err, "expected")
+
+	_, err = os.Stat(dest)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(dest + ".part")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, total, err := parseContentRange("bytes 100-199/1000")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(1000), total)
+
+	start, total, err = parseContentRange("bytes 100-199/*")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(-1), total)
+
+	_, _, err = parseContentRange("bytes 100-199/lots")
+	assert.Error(t, err)
+	_, _, err = parseContentRange("")
+	assert.Error(t, err)
+}
+
+func TestDownloadConnections(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 1},
+		{"1", 1},
+		{"4", 4},
+		{"0", 1},
+		{"-2", 1},
+		{"many", 1},
+		{"100", maxDownloadConnections},
+	}
+	for _, tt := range tests {
+		t.Setenv(downloadConnectionsEnv, tt.value)
+		assert.Equal(t, tt.want, DownloadConnections(), tt.value)
+	}
+}
+
+func TestDownloadRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", 0},
+		{"500000", 500000},
+		{"2MB", 2000000},
+		{"512k", 512000},
+		{"0", 0},
+		{"-1", 0},
+		{"fast", 0},
+	}
+	for _, tt := range tests {
+		t.Setenv(downloadRateEnv, tt.value)
+		assert.Equal(t, tt.want, DownloadRate(), tt.value)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 4000)
+
+	// Without a limiter the reader is passed through
+	r := bytes.NewReader(content)
+	assert.Equal(t, io.NopCloser(r), throttle(r, nil))
+
+	// 40000 bytes at 100000 bytes per second take at least 400ms, whether
+	// read by one reader or split across readers sharing the limiter
+	for _, readers := range []int{1, 4} {
+		limiter := &rateLimiter{rate: 100000}
+		part := len(content) / readers
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < readers; i++ {
+			wg.Add(1)
+			go func(chunk []byte) {
+				defer wg.Done()
+				got, err := io.ReadAll(throttle(bytes.NewReader(chunk), limiter))
+				assert.NoError(t, err)
+				assert.Equal(t, chunk, got)
+			}(content[i*part : (i+1)*part])
+		}
+		wg.Wait()
+		assert.GreaterOrEqual(t, time.Since(start), 390*time.Millisecond, "%d readers", readers)
+	}
+}
+
+func TestDownloadVMImageRateLimited(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 4000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "image.xz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	t.Setenv(downloadRateEnv, "100000")
+
+	dest := filepath.Join(t.TempDir(), "image.xz")
+	u, err := url2.Parse(srv.URL + "/image.xz")
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, DownloadVMImage(u, "image.xz", dest, int64(len(content)), true))
+	assert.GreaterOrEqual(t, time.Since(start), 390*time.Millisecond)
+
+	got, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
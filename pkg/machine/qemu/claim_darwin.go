@@ -8,22 +8,53 @@ import (
 	"os/user"
 	"path/filepath"
 	"time"
+
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/containers/podman/v4/pkg/machine/machelper"
+	"github.com/sirupsen/logrus"
 )
 
 func dockerClaimSupported() bool {
 	return true
 }
 
+// helperStatus returns the status of the helper of userName, recognizing
+// its links into the machine data directory, wherever that is
+func helperStatus(userName string) (*machelper.Status, error) {
+	dataDir, err := machine.DataDirPrefix()
+	if err != nil {
+		return nil, err
+	}
+	return machelper.GetStatus(userName, dataDir)
+}
+
 func dockerClaimHelperInstalled() bool {
 	u, err := user.Current()
 	if err != nil {
 		return false
 	}
 
-	labelName := fmt.Sprintf("com.github.containers.podman.helper-%s", u.Username)
-	fileName := filepath.Join("/Library", "LaunchDaemons", labelName+".plist")
-	info, err := os.Stat(fileName)
-	return err == nil && info.Mode().IsRegular()
+	status, err := helperStatus(u.Username)
+	return err == nil && status.Installed
+}
+
+// warnHelperTarget warns when the helper has linked docker.sock to a socket
+// other than links, the user global sockets forwarded to the running machine
+func warnHelperTarget(links []string) {
+	u, err := user.Current()
+	if err != nil {
+		return
+	}
+	status, err := helperStatus(u.Username)
+	if err != nil || !status.Managed() {
+		return
+	}
+	for _, link := range links {
+		if status.Link == link {
+			return
+		}
+	}
+	logrus.Warnf("%s is managed by podman-mac-helper but points at %s, not at this machine; run podman-mac-helper retarget %s to use it", machelper.DockerSock, status.Link, links[len(links)-1])
 }
 
 func claimDockerSock() bool {
@@ -7,6 +7,9 @@ func dockerClaimHelperInstalled() bool {
 	return false
 }
 
+func warnHelperTarget(links []string) {
+}
+
 func claimDockerSock() bool {
 	return false
 }
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"net/http"
@@ -26,8 +27,8 @@ import (
 
 	"github.com/containers/common/pkg/config"
 	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/containers/podman/v4/pkg/machine/machelper"
 	"github.com/containers/podman/v4/pkg/rootless"
-	"github.com/containers/podman/v4/utils"
 	"github.com/containers/storage/pkg/homedir"
 	"github.com/digitalocean/go-qemu/qmp"
 	"github.com/docker/go-units"
@@ -90,7 +91,7 @@ func (p *Virtualization) NewMachine(opts machine.InitOptions) (machine.VM, error
 	vm.RemoteUsername = opts.Username
 
 	// Add a random port for ssh
-	port, err := utils.GetRandomPort()
+	port, err := machine.AllocateSSHPort()
 	if err != nil {
 		return nil, err
 	}
@@ -114,28 +115,36 @@ func (p *Virtualization) NewMachine(opts machine.InitOptions) (machine.VM, error
 	if err := vm.setPIDSocket(); err != nil {
 		return nil, err
 	}
-	cmd := []string{execPath}
-	// Add memory
-	cmd = append(cmd, []string{"-m", strconv.Itoa(int(vm.Memory))}...)
-	// Add cpus
-	cmd = append(cmd, []string{"-smp", strconv.Itoa(int(vm.CPUs))}...)
-	// Add ignition file
-	cmd = append(cmd, []string{"-fw_cfg", "name=opt/com.coreos/config,file=" + vm.IgnitionFile.GetPath()}...)
-	// Add qmp socket
 	monitor, err := NewQMPMonitor("unix", vm.Name, defaultQMPTimeout)
 	if err != nil {
 		return nil, err
 	}
 	vm.QMPMonitor = monitor
-	cmd = append(cmd, []string{"-qmp", monitor.Network + ":" + monitor.Address.GetPath() + ",server=on,wait=off"}...)
+	if err := vm.setReadySocket(); err != nil {
+		return nil, err
+	}
+	vm.CmdLine = vm.baseCmdLine(execPath)
+	return vm, nil
+}
+
+// baseCmdLine returns the start of the qemu command line, up to the
+// architecture options, mounts and image added by Init. The files and
+// sockets in it are named after the machine.
+func (v *MachineVM) baseCmdLine(execPath string) []string {
+	cmd := []string{execPath}
+	// Add memory
+	cmd = append(cmd, []string{"-m", strconv.Itoa(int(v.Memory))}...)
+	// Add cpus
+	cmd = append(cmd, []string{"-smp", strconv.Itoa(int(v.CPUs))}...)
+	// Add ignition file
+	cmd = append(cmd, []string{"-fw_cfg", "name=opt/com.coreos/config,file=" + v.IgnitionFile.GetPath()}...)
+	// Add qmp socket
+	cmd = append(cmd, []string{"-qmp", v.QMPMonitor.Network + ":" + v.QMPMonitor.Address.GetPath() + ",server=on,wait=off"}...)
 
 	// Add network
 	// Right now the mac address is hardcoded so that the host networking gives it a specific IP address.  This is
 	// why we can only run one vm at a time right now
 	cmd = append(cmd, []string{"-netdev", "socket,id=vlan,fd=3", "-device", "virtio-net-pci,netdev=vlan,mac=5a:94:ef:e4:0c:ee"}...)
-	if err := vm.setReadySocket(); err != nil {
-		return nil, err
-	}
 
 	// Add serial port for readiness
 	cmd = append(cmd, []string{
@@ -143,11 +152,10 @@ func (p *Virtualization) NewMachine(opts machine.InitOptions) (machine.VM, error
 		// qemu needs to establish the long name; other connections can use the symlink'd
 		// Note both id and chardev start with an extra "a" because qemu requires that it
 		// starts with an letter but users can also use numbers
-		"-chardev", "socket,path=" + vm.ReadySocket.Path + ",server=on,wait=off,id=a" + vm.Name + "_ready",
-		"-device", "virtserialport,chardev=a" + vm.Name + "_ready" + ",name=org.fedoraproject.port.0",
-		"-pidfile", vm.VMPidFilePath.GetPath()}...)
-	vm.CmdLine = cmd
-	return vm, nil
+		"-chardev", "socket,path=" + v.ReadySocket.Path + ",server=on,wait=off,id=a" + v.Name + "_ready",
+		"-device", "virtserialport,chardev=a" + v.Name + "_ready" + ",name=org.fedoraproject.port.0",
+		"-pidfile", v.VMPidFilePath.GetPath()}...)
+	return cmd
 }
 
 // migrateVM takes the old configuration structure and migrates it
@@ -271,7 +279,7 @@ func (v *MachineVM) Init(opts machine.InitOptions) (bool, error) {
 			return false, err
 		}
 		v.ImagePath = *uncompressedFile
-		if err := machine.DownloadImage(dd); err != nil {
+		if err := machine.DownloadImage(dd, opts.Quiet); err != nil {
 			return false, err
 		}
 	default:
@@ -287,7 +295,7 @@ func (v *MachineVM) Init(opts machine.InitOptions) (bool, error) {
 			return false, err
 		}
 		v.ImagePath = *imagePath
-		if err := machine.DownloadImage(g); err != nil {
+		if err := machine.DownloadImage(g, opts.Quiet); err != nil {
 			return false, err
 		}
 	}
@@ -673,6 +681,8 @@ func (v *MachineVM) Start(name string, opts machine.StartOptions) error {
 		}
 	}
 
+	// Written to the config along with Starting
+	v.LastUp = time.Now()
 	v.waitAPIAndPrintInfo(forwardState, forwardSock, opts.NoInfo)
 	return nil
 }
@@ -747,8 +757,12 @@ func (v *MachineVM) checkStatus(monitor *qmp.SocketMonitor) (machine.Status, err
 }
 
 // Stop uses the qmp monitor to call a system_powerdown
-func (v *MachineVM) Stop(_ string, _ machine.StopOptions) error {
+func (v *MachineVM) Stop(_ string, opts machine.StopOptions) error {
 	var disconnected bool
+	timeout := machine.DefaultStopTimeout
+	if opts.Timeout != nil {
+		timeout = *opts.Timeout
+	}
 	// check if the qmp socket is there. if not, qemu instance is gone
 	if _, err := os.Stat(v.QMPMonitor.Address.GetPath()); os.IsNotExist(err) {
 		// Right now it is NOT an error to stop a stopped machine
@@ -759,12 +773,16 @@ func (v *MachineVM) Stop(_ string, _ machine.StopOptions) error {
 	if err != nil {
 		return err
 	}
-	// Simple JSON formation for the QAPI
+	// Simple JSON formation for the QAPI. Without a timeout qemu exits
+	// right away instead of asking the guest to power down.
 	stopCommand := struct {
 		Execute string `json:"execute"`
 	}{
 		Execute: "system_powerdown",
 	}
+	if timeout == 0 {
+		stopCommand.Execute = "quit"
+	}
 	input, err := json.Marshal(stopCommand)
 	if err != nil {
 		return err
@@ -861,7 +879,16 @@ func (v *MachineVM) Stop(_ string, _ machine.StopOptions) error {
 	}
 
 	fmt.Println("Waiting for VM to exit...")
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	killed := false
 	for isProcessAlive(vmPid) {
+		if !killed && time.Now().After(deadline) {
+			logrus.Warnf("Machine %q did not shut down within %d seconds, terminating it", v.Name, timeout)
+			if proc, err := os.FindProcess(vmPid); err == nil {
+				_ = proc.Kill()
+			}
+			killed = true
+		}
 		time.Sleep(500 * time.Millisecond)
 	}
 
@@ -923,6 +950,11 @@ func (v *MachineVM) Remove(_ string, opts machine.RemoveOptions) (string, func()
 	if !opts.SaveKeys {
 		files = append(files, v.IdentityPath, v.IdentityPath+".pub")
 	}
+	// A new machine with the same name has a different host key
+	knownHosts := machine.KnownHostsPath(v.IdentityPath)
+	if _, err := os.Stat(knownHosts); err == nil {
+		files = append(files, knownHosts)
+	}
 	if !opts.SaveIgnition {
 		files = append(files, v.getIgnitionFile())
 	}
@@ -980,6 +1012,130 @@ func (v *MachineVM) Remove(_ string, opts machine.RemoveOptions) (string, func()
 	}, nil
 }
 
+func (v *MachineVM) Export(_ string, _ string) error {
+	return machine.ErrNotImplemented
+}
+
+func (v *MachineVM) Logs(_ string, _ io.Writer) error {
+	return machine.ErrNotImplemented
+}
+
+func (v *MachineVM) UpdateOS(_ string) (bool, error) {
+	return false, machine.ErrNotImplemented
+}
+
+func (v *MachineVM) PlanInit(_ machine.InitOptions) (*machine.InitPlan, error) {
+	return nil, machine.ErrNotImplemented
+}
+
+// Rename changes the name of a stopped machine, moving the files,
+// sockets and connections that are named after it
+func (v *MachineVM) Rename(_ string, newName string) (err error) {
+	state, err := v.State(false)
+	if err != nil {
+		return err
+	}
+	if state == machine.Running || state == machine.Starting {
+		return fmt.Errorf("running vm %q cannot be renamed", v.Name)
+	}
+
+	oldName := v.Name
+	oldConfigPath := v.ConfigPath.GetPath()
+	newConfigPath := filepath.Join(filepath.Dir(oldConfigPath), newName+".json")
+	if _, err := os.Stat(newConfigPath); err == nil {
+		return fmt.Errorf("%s: %w", newName, machine.ErrVMAlreadyExists)
+	}
+	// Refuse the rename before changing anything if a file of the new name
+	// is in the way, such as ssh keys kept by rm --save-keys
+	files := append([]string{v.getIgnitionFile(), v.getImageFile(), v.IdentityPath, v.IdentityPath + ".pub", machine.KnownHostsPath(v.IdentityPath)}, v.archRemovalFiles()...)
+	if err := machine.CheckRenameMachineFiles(files, oldName, newName); err != nil {
+		return err
+	}
+	if err := machine.CheckRenameConnections(oldName, newName); err != nil {
+		return err
+	}
+
+	// Undo what was changed so far if a later step fails, so the machine
+	// is left under its old name
+	var cleanups []func()
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}()
+
+	newFiles, err := machine.RenameMachineFiles(files, oldName, newName)
+	if err != nil {
+		return err
+	}
+	old := *v
+	cleanups = append(cleanups, func() {
+		*v = old
+		if _, err := machine.RenameMachineFiles(newFiles, newName, oldName); err != nil {
+			logrus.Error(err)
+		}
+	})
+
+	v.Name = newName
+	v.IgnitionFile = machine.VMFile{Path: newFiles[0]}
+	v.ImagePath = machine.VMFile{Path: newFiles[1]}
+	v.IdentityPath = newFiles[2]
+	monitor, err := NewQMPMonitor(v.QMPMonitor.Network, newName, v.QMPMonitor.Timeout)
+	if err != nil {
+		return err
+	}
+	v.QMPMonitor = monitor
+	if err := v.setReadySocket(); err != nil {
+		return err
+	}
+	if err := v.setPIDSocket(); err != nil {
+		return err
+	}
+
+	// Regenerate the qemu command line from the new name, keeping the
+	// mounts, which are not derived from it
+	cmdLine := v.baseCmdLine(old.CmdLine[0])
+	cmdLine = append(cmdLine, v.addArchOptions()...)
+	cmdLine = append(cmdLine, virtfsArgs(old.CmdLine)...)
+	v.CmdLine = append(cmdLine, "-drive", "if=virtio,file="+v.getImageFile())
+
+	if err := v.writeConfig(); err != nil {
+		return err
+	}
+	cleanups = append(cleanups, func() {
+		if err := os.Remove(newConfigPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Error(err)
+		}
+	})
+	if err := machine.RenameConnections(oldName, newName, v.IdentityPath); err != nil {
+		return err
+	}
+	cleanups = append(cleanups, func() {
+		if err := machine.RenameConnections(newName, oldName, old.IdentityPath); err != nil {
+			logrus.Error(err)
+		}
+	})
+	if err := os.Remove(oldConfigPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// virtfsArgs returns the -virtfs arguments of a qemu command line
+func virtfsArgs(cmdLine []string) []string {
+	var args []string
+	for i := 0; i < len(cmdLine)-1; i++ {
+		if cmdLine[i] == "-virtfs" {
+			args = append(args, cmdLine[i], cmdLine[i+1])
+			i++
+		}
+	}
+	return args
+}
+
 func (v *MachineVM) State(bypass bool) (machine.Status, error) {
 	// Check if qmp socket path exists
 	if _, err := os.Stat(v.QMPMonitor.Address.GetPath()); os.IsNotExist(err) {
@@ -1036,6 +1192,9 @@ func (v *MachineVM) SSH(_ string, opts machine.SSHOptions) error {
 		return fmt.Errorf("vm %q is not running", v.Name)
 	}
 
+	if err := machine.CheckMachineUser(opts.Username, v.RemoteUsername); err != nil {
+		return err
+	}
 	username := opts.Username
 	if username == "" {
 		username = v.RemoteUsername
@@ -1044,8 +1203,16 @@ func (v *MachineVM) SSH(_ string, opts machine.SSHOptions) error {
 	sshDestination := username + "@localhost"
 	port := strconv.Itoa(v.Port)
 
-	args := []string{"-i", v.IdentityPath, "-p", port, sshDestination,
-		"-o", "StrictHostKeyChecking=no", "-o", "LogLevel=ERROR", "-o", "SetEnv=LC_ALL="}
+	extraArgs, err := machine.SSHOptionArgs(opts.Options)
+	if err != nil {
+		return err
+	}
+
+	args := append(machine.IdentityArgs(v.IdentityPath), "-p", port)
+	args = append(args, extraArgs...)
+	args = append(args, sshDestination)
+	args = append(args, machine.HostKeyArgs(v.IdentityPath, opts.NoHostKeyCheck)...)
+	args = append(args, "-o", "LogLevel=ERROR", "-o", "SetEnv=LC_ALL=")
 	if len(opts.Args) > 0 {
 		args = append(args, opts.Args...)
 	} else {
@@ -1059,6 +1226,33 @@ func (v *MachineVM) SSH(_ string, opts machine.SSHOptions) error {
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
+	return machine.SSHCommandError(v.Name, cmd.Run())
+}
+
+// Cp copies a file or directory between the host and the machine with scp
+func (v *MachineVM) Cp(_ string, opts machine.CpOptions) error {
+	state, err := v.State(true)
+	if err != nil {
+		return err
+	}
+	if state != machine.Running {
+		return fmt.Errorf("vm %q is not running", v.Name)
+	}
+
+	if err := machine.CheckMachineUser(opts.Username, v.RemoteUsername); err != nil {
+		return err
+	}
+	username := opts.Username
+	if username == "" {
+		username = v.RemoteUsername
+	}
+
+	args := machine.CpArgs(v.IdentityPath, v.Port, username, opts)
+	cmd := exec.Command("scp", args...)
+	logrus.Debugf("Executing: scp %v\n", args)
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
@@ -1143,12 +1337,19 @@ func getVMInfos() ([]*machine.ListResponse, error) {
 			listEntry.Port = vm.Port
 			listEntry.RemoteUsername = vm.RemoteUsername
 			listEntry.IdentityPath = vm.IdentityPath
+			listEntry.Rootful = vm.Rootful
 			listEntry.CreatedAt = vm.Created
 			listEntry.Starting = vm.Starting
 
 			if listEntry.CreatedAt.IsZero() {
-				listEntry.CreatedAt = time.Now()
+				// Configs written before the creation time was recorded
+				// were last written around when the machine was created,
+				// so take that once and keep it from then on
 				vm.Created = time.Now()
+				if info, err := d.Info(); err == nil {
+					vm.Created = info.ModTime()
+				}
+				listEntry.CreatedAt = vm.Created
 				if err := vm.writeConfig(); err != nil {
 					return err
 				}
@@ -1179,6 +1380,10 @@ func getVMInfos() ([]*machine.ListResponse, error) {
 	return listed, err
 }
 
+func (p *Virtualization) Import(_ string, _ string) (machine.VM, error) {
+	return nil, machine.ErrNotImplemented
+}
+
 func (p *Virtualization) IsValidVMName(name string) (bool, error) {
 	infos, err := getVMInfos()
 	if err != nil {
@@ -1316,8 +1521,26 @@ func (v *MachineVM) setupAPIForwarding(cmd []string) ([]string, string, apiForwa
 		}
 	}
 
-	if !alreadyLinked(link, dockerSock) {
+	// A helper installed with --rootful links docker.sock to a second user
+	// global link, which only leads to a running rootful machine
+	links := []string{link}
+	rootLink := filepath.Join(filepath.Dir(link), machelper.RootfulUserSocket)
+	if v.Rootful {
+		if !alreadyLinked(socket.GetPath(), rootLink) {
+			_ = os.Remove(rootLink)
+			if err = os.Symlink(socket.GetPath(), rootLink); err != nil {
+				logrus.Warnf("could not create user global rootful API forwarding link: %s", err.Error())
+			}
+		}
+		links = append(links, rootLink)
+	} else if alreadyLinked(socket.GetPath(), rootLink) {
+		// The machine was changed to rootless
+		_ = os.Remove(rootLink)
+	}
+
+	if !linkedToAny(dockerSock, links) {
 		if checkSockInUse(dockerSock) {
+			warnHelperTarget(links)
 			return cmd, socket.GetPath(), machineLocal
 		}
 
@@ -1325,6 +1548,11 @@ func (v *MachineVM) setupAPIForwarding(cmd []string) ([]string, string, apiForwa
 			logrus.Warn("podman helper is installed, but was not able to claim the global docker sock")
 			return cmd, socket.GetPath(), machineLocal
 		}
+		if !linkedToAny(dockerSock, links) {
+			// The helper targets the socket of rootful machines, or a custom one
+			warnHelperTarget(links)
+			return cmd, socket.GetPath(), machineLocal
+		}
 	}
 
 	return cmd, dockerSock, dockerGlobal
@@ -1341,7 +1569,7 @@ func (v *MachineVM) userGlobalSocketLink() (string, error) {
 		return "", err
 	}
 	// User global socket is located in parent directory of machine dirs (one per user)
-	return filepath.Join(filepath.Dir(path), "podman.sock"), err
+	return filepath.Join(filepath.Dir(path), machelper.UserSocket), err
 }
 
 func (v *MachineVM) forwardSocketPath() (*machine.VMFile, error) {
@@ -1436,6 +1664,16 @@ func alreadyLinked(target string, link string) bool {
 	return err == nil && read == target
 }
 
+// linkedToAny reports whether link points at one of targets
+func linkedToAny(link string, targets []string) bool {
+	for _, target := range targets {
+		if alreadyLinked(target, link) {
+			return true
+		}
+	}
+	return false
+}
+
 func waitAndPingAPI(sock string) {
 	client := http.Client{
 		Transport: &http.Transport{
@@ -1599,10 +1837,13 @@ func (v *MachineVM) Inspect() (*machine.InspectInfo, error) {
 		Created:        v.Created,
 		Image:          v.ImageConfig,
 		LastUp:         v.LastUp,
+		Mounts:         v.Mounts,
 		Name:           v.Name,
 		Resources:      v.ResourceConfig,
+		Rootful:        v.Rootful,
 		SSHConfig:      v.SSHConfig,
 		State:          state,
+		VMType:         vmtype.String(),
 	}, nil
 }
 
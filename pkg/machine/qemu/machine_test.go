@@ -24,6 +24,12 @@ func TestEditCmd(t *testing.T) {
 	require.Equal(t, vm.CmdLine, []string{"command", "-flag", "newvalue", "-anotherflag", "anothervalue"})
 }
 
+func TestVirtfsArgs(t *testing.T) {
+	cmdLine := []string{"qemu", "-m", "2048", "-virtfs", "local,path=/a,mount_tag=vol0", "-cpu", "host", "-virtfs", "local,path=/b,mount_tag=vol1"}
+	assert.Equal(t, []string{"-virtfs", "local,path=/a,mount_tag=vol0", "-virtfs", "local,path=/b,mount_tag=vol1"}, virtfsArgs(cmdLine))
+	assert.Empty(t, virtfsArgs([]string{"qemu", "-virtfs"}))
+}
+
 func TestPropagateHostEnv(t *testing.T) {
 	t.Setenv("SSL_CERT_FILE", "/some/foo.cert")
 	t.Setenv("SSL_CERT_DIR", "/some/my/certs")
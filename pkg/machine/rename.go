@@ -0,0 +1,77 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RenameMachineFile renames a file that is named after a machine, such as
+// its ssh key (<name>), ignition file (<name>.ign) or image (<name>_image),
+// and returns its new path. Paths not named after the machine, and files
+// that do not exist, are left alone and returned unchanged.
+func RenameMachineFile(path, oldName, newName string) (string, error) {
+	newPath := renamedMachinePath(path, oldName, newName)
+	if newPath == path {
+		return path, nil
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return "", fmt.Errorf("cannot rename %q: %q already exists", path, newPath)
+	}
+	if err := os.Rename(path, newPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		return "", err
+	}
+	return newPath, nil
+}
+
+// CheckRenameMachineFiles returns an error if any of paths can not be
+// renamed by RenameMachineFile because its new path already exists, so a
+// rename can be refused before anything is changed.
+func CheckRenameMachineFiles(paths []string, oldName, newName string) error {
+	for _, path := range paths {
+		newPath := renamedMachinePath(path, oldName, newName)
+		if newPath == path {
+			continue
+		}
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("cannot rename %q: %q already exists", path, newPath)
+		}
+	}
+	return nil
+}
+
+// RenameMachineFiles renames each of paths with RenameMachineFile and
+// returns their new paths in the same order. If one of them fails, the
+// files renamed so far are renamed back before the error is returned.
+func RenameMachineFiles(paths []string, oldName, newName string) ([]string, error) {
+	newPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		newPath, err := RenameMachineFile(path, oldName, newName)
+		if err != nil {
+			if _, undoErr := RenameMachineFiles(newPaths, newName, oldName); undoErr != nil {
+				logrus.Error(undoErr)
+			}
+			return nil, err
+		}
+		newPaths = append(newPaths, newPath)
+	}
+	return newPaths, nil
+}
+
+func renamedMachinePath(path, oldName, newName string) string {
+	dir, base := filepath.Split(path)
+	if base != oldName && !strings.HasPrefix(base, oldName+".") && !strings.HasPrefix(base, oldName+"_") {
+		return path
+	}
+	return filepath.Join(dir, newName+strings.TrimPrefix(base, oldName))
+}
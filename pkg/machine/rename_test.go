@@ -0,0 +1,79 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameMachineFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"vm", "vm.ign", "vm_fedora-coreos.qcow2", "vm2", "other"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"vm", "dev"},
+		{"vm.ign", "dev.ign"},
+		{"vm_fedora-coreos.qcow2", "dev_fedora-coreos.qcow2"},
+		// Only files named after the machine are renamed
+		{"vm2", "vm2"},
+		{"other", "other"},
+		// Missing files are left alone
+		{"vm.pub", "vm.pub"},
+	}
+	for _, tt := range tests {
+		got, err := RenameMachineFile(filepath.Join(dir, tt.file), "vm", "dev")
+		require.NoError(t, err, tt.file)
+		assert.Equal(t, filepath.Join(dir, tt.want), got, tt.file)
+		if tt.file != "vm.pub" {
+			_, err = os.Stat(got)
+			assert.NoError(t, err, tt.file)
+		}
+	}
+}
+
+func TestRenameMachineFileExists(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vm"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dev"), nil, 0644))
+
+	_, err := RenameMachineFile(filepath.Join(dir, "vm"), "vm", "dev")
+	assert.Error(t, err)
+}
+
+func TestRenameMachineFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"vm", "vm.pub", "vm.ign", "dev.ign"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	paths := []string{filepath.Join(dir, "vm"), filepath.Join(dir, "vm.pub"), filepath.Join(dir, "vm.ign")}
+
+	// dev.ign is in the way, so nothing is renamed
+	assert.Error(t, CheckRenameMachineFiles(paths, "vm", "dev"))
+	_, err := RenameMachineFiles(paths, "vm", "dev")
+	assert.Error(t, err)
+	for _, name := range []string{"vm", "vm.pub", "vm.ign"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err, name)
+	}
+	for _, name := range []string{"dev", "dev.pub"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.ErrorIs(t, err, os.ErrNotExist, name)
+	}
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "dev.ign")))
+	assert.NoError(t, CheckRenameMachineFiles(paths, "vm", "dev"))
+	got, err := RenameMachineFiles(paths, "vm", "dev")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "dev"), filepath.Join(dir, "dev.pub"), filepath.Join(dir, "dev.ign")}, got)
+}
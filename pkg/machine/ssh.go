@@ -0,0 +1,143 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hostKeyAlias names the host key in the known_hosts file of a machine,
+// so the entry does not depend on the ssh port, which can change
+const hostKeyAlias = "podman-machine"
+
+// sshAgentEnv lets ssh use an ssh-agent for the machine identity, which is
+// needed for passphrase-protected keys
+const sshAgentEnv = "PODMAN_MACHINE_SSH_AGENT"
+
+// sshTransportExitCode is the status ssh exits with when it fails itself,
+// rather than the remote command
+const sshTransportExitCode = 255
+
+// ExitCodeError is returned when a command run in a machine over ssh
+// exits with a non-zero status, so callers can exit with the same status
+type ExitCodeError struct {
+	Code int
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("remote command exited with status %d", e.Code)
+}
+
+// SSHCommandError converts the error returned by running ssh against the
+// named machine. A non-zero exit of the remote command becomes an
+// ExitCodeError, while failures of ssh itself, such as the connection
+// being refused, are reported as regular errors.
+func SSHCommandError(name string, err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	if exitErr.ExitCode() == sshTransportExitCode {
+		return fmt.Errorf("could not connect to vm %q over ssh: %w", name, err)
+	}
+	return &ExitCodeError{Code: exitErr.ExitCode()}
+}
+
+// CheckMachineUser verifies that a username given for a machine names one
+// of the accounts its key is installed for, root and the configured remote
+// user, so a typo is reported clearly rather than as an ssh authentication
+// failure. An empty username selects the remote user and is always valid.
+func CheckMachineUser(username, remoteUsername string) error {
+	if len(username) == 0 || username == "root" || username == remoteUsername {
+		return nil
+	}
+	return fmt.Errorf("unknown machine user %q; valid users are root, %s", username, remoteUsername)
+}
+
+// reservedSSHOptions are set by podman machine to reach the machine and
+// can not be changed with extra ssh options
+var reservedSSHOptions = map[string]bool{
+	"hostname":     true,
+	"identityfile": true,
+	"port":         true,
+	"user":         true,
+}
+
+// SSHOptionArgs validates extra ssh options, given as key=value or
+// "key value", and returns them as -o arguments for ssh
+func SSHOptionArgs(options []string) ([]string, error) {
+	args := make([]string, 0, 2*len(options))
+	for _, option := range options {
+		key, _, found := strings.Cut(strings.TrimSpace(option), "=")
+		if !found {
+			key, _, found = strings.Cut(key, " ")
+		}
+		key = strings.TrimSpace(key)
+		if !found || len(key) == 0 {
+			return nil, fmt.Errorf("invalid ssh option %q, expected key=value", option)
+		}
+		if reservedSSHOptions[strings.ToLower(key)] {
+			return nil, fmt.Errorf("ssh option %s can not be set, it is managed by podman machine", key)
+		}
+		args = append(args, "-o", option)
+	}
+	return args, nil
+}
+
+// KnownHostsPath returns the known_hosts file of the machine that uses the
+// given ssh identity, which is stored next to the identity
+func KnownHostsPath(identityPath string) string {
+	return identityPath + ".known_hosts"
+}
+
+// HostKeyArgs returns the ssh arguments that pin the host key of the
+// machine using the given identity, accepting it on the first connection.
+// With noCheck any host key is accepted and nothing is recorded.
+func HostKeyArgs(identityPath string, noCheck bool) []string {
+	if noCheck {
+		return []string{"-o", "UserKnownHostsFile=/dev/null", "-o", "StrictHostKeyChecking=no"}
+	}
+	// ssh reads forward slashes on Windows too, and the quotes keep paths
+	// with spaces intact
+	knownHosts := filepath.ToSlash(KnownHostsPath(identityPath))
+	return []string{"-o", fmt.Sprintf("UserKnownHostsFile=%q", knownHosts),
+		"-o", "StrictHostKeyChecking=accept-new", "-o", "HostKeyAlias=" + hostKeyAlias}
+}
+
+// IdentityArgs returns the ssh arguments that select the key to log into a
+// machine with. By default only the identity file of the machine is
+// passed. When PODMAN_MACHINE_SSH_AGENT is true, the key is added to the
+// ssh-agent once it is unlocked, so a passphrase is only asked for once,
+// and without the identity file the agent alone is used.
+func IdentityArgs(identityPath string) []string {
+	if !useSSHAgent() {
+		return []string{"-i", identityPath}
+	}
+	if _, err := os.Stat(identityPath); err != nil {
+		logrus.Debugf("Using the ssh-agent without the identity file %s: %v", identityPath, err)
+		return nil
+	}
+	return []string{"-i", identityPath, "-o", "AddKeysToAgent=yes"}
+}
+
+func useSSHAgent() bool {
+	value, found := os.LookupEnv(sshAgentEnv)
+	if !found || len(value) == 0 {
+		return false
+	}
+	use, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.Warnf("Ignoring invalid %s value %q", sshAgentEnv, value)
+		return false
+	}
+	return use
+}
@@ -0,0 +1,84 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHCommandError(t *testing.T) {
+	assert.NoError(t, SSHCommandError("vm", nil))
+
+	other := errors.New("ssh not found")
+	assert.Equal(t, other, SSHCommandError("vm", other))
+
+	err := SSHCommandError("vm", exec.Command("sh", "-c", "exit 3").Run())
+	var exitErr *ExitCodeError
+	if assert.ErrorAs(t, err, &exitErr) {
+		assert.Equal(t, 3, exitErr.Code)
+	}
+
+	err = SSHCommandError("vm", exec.Command("sh", "-c", "exit 255").Run())
+	assert.False(t, errors.As(err, &exitErr))
+	assert.ErrorContains(t, err, `could not connect to vm "vm" over ssh`)
+}
+
+func TestCheckMachineUser(t *testing.T) {
+	for _, username := range []string{"", "root", "core"} {
+		assert.NoError(t, CheckMachineUser(username, "core"), username)
+	}
+	err := CheckMachineUser("cor", "core")
+	assert.EqualError(t, err, `unknown machine user "cor"; valid users are root, core`)
+}
+
+func TestSSHOptionArgs(t *testing.T) {
+	args, err := SSHOptionArgs([]string{"ProxyJump=bastion", "ServerAliveInterval 30"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-o", "ProxyJump=bastion", "-o", "ServerAliveInterval 30"}, args)
+
+	args, err = SSHOptionArgs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+
+	for _, option := range []string{"Port=2222", "identityfile /tmp/key", " User = root", "HostName=other"} {
+		_, err = SSHOptionArgs([]string{option})
+		assert.ErrorContains(t, err, "managed by podman machine", option)
+	}
+	for _, option := range []string{"ProxyJump", "=value", ""} {
+		_, err = SSHOptionArgs([]string{option})
+		assert.ErrorContains(t, err, "expected key=value", option)
+	}
+}
+
+func TestHostKeyArgs(t *testing.T) {
+	assert.Equal(t, []string{"-o", "UserKnownHostsFile=/dev/null", "-o", "StrictHostKeyChecking=no"},
+		HostKeyArgs("/home/user/.ssh/vm", true))
+	assert.Equal(t, []string{"-o", `UserKnownHostsFile="/home/user/.ssh/vm.known_hosts"`,
+		"-o", "StrictHostKeyChecking=accept-new", "-o", "HostKeyAlias=podman-machine"},
+		HostKeyArgs("/home/user/.ssh/vm", false))
+}
+
+func TestIdentityArgs(t *testing.T) {
+	identity := filepath.Join(t.TempDir(), "machine")
+	assert.Equal(t, []string{"-i", identity}, IdentityArgs(identity))
+
+	t.Setenv(sshAgentEnv, "false")
+	assert.Equal(t, []string{"-i", identity}, IdentityArgs(identity))
+
+	t.Setenv(sshAgentEnv, "bogus")
+	assert.Equal(t, []string{"-i", identity}, IdentityArgs(identity))
+
+	// Without the identity file only the agent is used
+	t.Setenv(sshAgentEnv, "true")
+	assert.Empty(t, IdentityArgs(identity))
+
+	assert.NoError(t, os.WriteFile(identity, []byte("key"), 0600))
+	assert.Equal(t, []string{"-i", identity, "-o", "AddKeysToAgent=yes"}, IdentityArgs(identity))
+}
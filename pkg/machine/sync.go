@@ -0,0 +1,315 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SyncOptions controls how a host directory is synchronized into a machine
+type SyncOptions struct {
+	// Excludes are patterns matched against the name and the relative
+	// path of each file or directory; matches are not transferred
+	Excludes []string
+	// Options are extra ssh options in the -o form, such as
+	// ProxyJump=bastion
+	Options []string
+	// NoHostKeyCheck accepts any host key instead of pinning the key
+	// of the machine in its known_hosts file
+	NoHostKeyCheck bool
+}
+
+// SyncReport summarizes the result of a sync
+type SyncReport struct {
+	// Transferred is the number of files copied into the machine
+	Transferred int
+	// Skipped is the number of files already up to date in the machine
+	Skipped int
+}
+
+type remoteFile struct {
+	size  int64
+	mtime int64
+}
+
+var rsyncStatsRegex = regexp.MustCompile(`(?m)^Number of (regular files transferred|files): ([\d,]+)(?: \(reg: ([\d,]+))?`)
+
+// Sync incrementally copies the contents of the host directory src into
+// the guest directory dest over ssh. Only files that are missing in the guest,
+// or that differ in size or modification time, are transferred. rsync is used
+// when it is available on both the host and the guest, otherwise a tar
+// stream is piped over the ssh connection.
+func Sync(sshConfig SSHConfig, src, dest string, opts SyncOptions) (*SyncReport, error) {
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("sync source %q is not a directory", src)
+	}
+
+	// Catch invalid ssh options before anything runs
+	if _, err := sshArgs(sshConfig, opts); err != nil {
+		return nil, err
+	}
+
+	if err := runSSH(sshConfig, opts, nil, io.Discard, "mkdir -p "+shellQuote(dest)); err != nil {
+		return nil, fmt.Errorf("could not create %q in machine: %w", dest, err)
+	}
+
+	if canRsync(sshConfig, opts) {
+		logrus.Debugf("Syncing %s to %s using rsync", src, dest)
+		return rsync(sshConfig, src, dest, opts)
+	}
+
+	logrus.Debugf("Syncing %s to %s using tar over ssh", src, dest)
+	return tarSync(sshConfig, src, dest, opts)
+}
+
+// sshArgs returns the ssh arguments to reach the machine, handling the host
+// key and extra options the same way as podman machine ssh
+func sshArgs(sshConfig SSHConfig, opts SyncOptions) ([]string, error) {
+	extraArgs, err := SSHOptionArgs(opts.Options)
+	if err != nil {
+		return nil, err
+	}
+	args := append(IdentityArgs(sshConfig.IdentityPath), "-p", strconv.Itoa(sshConfig.Port))
+	args = append(args, HostKeyArgs(sshConfig.IdentityPath, opts.NoHostKeyCheck)...)
+	args = append(args, extraArgs...)
+	return append(args, "-o", "LogLevel=ERROR"), nil
+}
+
+func runSSH(sshConfig SSHConfig, opts SyncOptions, stdin io.Reader, stdout io.Writer, command string) error {
+	args, err := sshArgs(sshConfig, opts)
+	if err != nil {
+		return err
+	}
+	args = append(args, sshConfig.RemoteUsername+"@localhost", command)
+	logrus.Debugf("Executing: ssh %v", args)
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func canRsync(sshConfig SSHConfig, opts SyncOptions) bool {
+	// Native Windows rsync ports disagree on how drive paths are spelled,
+	// so always use the tar stream there
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	if _, err := exec.LookPath("rsync"); err != nil {
+		return false
+	}
+	return runSSH(sshConfig, opts, nil, io.Discard, "command -v rsync") == nil
+}
+
+func rsync(sshConfig SSHConfig, src, dest string, opts SyncOptions) (*SyncReport, error) {
+	sshCmdArgs, err := sshArgs(sshConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	quoted := make([]string, 0, len(sshCmdArgs)+1)
+	quoted = append(quoted, "ssh")
+	for _, arg := range sshCmdArgs {
+		quoted = append(quoted, shellQuote(arg))
+	}
+
+	args := []string{"-a", "--stats", "-e", strings.Join(quoted, " ")}
+	for _, exclude := range opts.Excludes {
+		args = append(args, "--exclude", exclude)
+	}
+	args = append(args, src+"/", fmt.Sprintf("%s@localhost:%s/", sshConfig.RemoteUsername, dest))
+
+	var out bytes.Buffer
+	logrus.Debugf("Executing: rsync %v", args)
+	cmd := exec.Command("rsync", args...)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("rsync failed: %w", err)
+	}
+
+	return parseRsyncStats(out.String()), nil
+}
+
+func parseRsyncStats(stats string) *SyncReport {
+	var transferred, regular int
+	for _, match := range rsyncStatsRegex.FindAllStringSubmatch(stats, -1) {
+		switch match[1] {
+		case "regular files transferred":
+			transferred, _ = strconv.Atoi(strings.ReplaceAll(match[2], ",", ""))
+		case "files":
+			regular, _ = strconv.Atoi(strings.ReplaceAll(match[3], ",", ""))
+		}
+	}
+
+	report := &SyncReport{Transferred: transferred}
+	if regular > transferred {
+		report.Skipped = regular - transferred
+	}
+	return report
+}
+
+func tarSync(sshConfig SSHConfig, src, dest string, opts SyncOptions) (*SyncReport, error) {
+	remote, err := listRemoteFiles(sshConfig, opts, dest)
+	if err != nil {
+		return nil, fmt.Errorf("could not list files in machine: %w", err)
+	}
+
+	report := new(SyncReport)
+	var pending []string
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == src {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if isExcluded(rel, opts.Excludes) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if existing, ok := remote[rel]; ok && existing.size == info.Size() && existing.mtime == info.ModTime().Unix() {
+			report.Skipped++
+			return nil
+		}
+		pending = append(pending, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		return report, nil
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeTar(writer, src, pending))
+	}()
+
+	if err := runSSH(sshConfig, opts, reader, io.Discard, "tar -xf - -C "+shellQuote(dest)); err != nil {
+		_ = reader.CloseWithError(err)
+		return nil, fmt.Errorf("could not extract files in machine: %w", err)
+	}
+
+	report.Transferred = len(pending)
+	return report, nil
+}
+
+func listRemoteFiles(sshConfig SSHConfig, opts SyncOptions, dest string) (map[string]remoteFile, error) {
+	var out bytes.Buffer
+	command := fmt.Sprintf(`cd %s && find . -type f -printf '%%P\t%%s\t%%T@\n'`, shellQuote(dest))
+	if err := runSSH(sshConfig, opts, nil, &out, command); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]remoteFile)
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		seconds, _, _ := strings.Cut(fields[2], ".")
+		mtime, err := strconv.ParseInt(seconds, 10, 64)
+		if err != nil {
+			continue
+		}
+		files[fields[0]] = remoteFile{size: size, mtime: mtime}
+	}
+	return files, scanner.Err()
+}
+
+func writeTar(w io.Writer, src string, files []string) error {
+	tw := tar.NewWriter(w)
+	for _, rel := range files {
+		if err := addTarFile(tw, src, rel); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func addTarFile(tw *tar.Writer, src, rel string) error {
+	f, err := os.Open(filepath.Join(src, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = rel
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// isExcluded reports whether the slash separated relative path, or
+// its base name, matches any of the exclude patterns
+func isExcluded(rel string, excludes []string) bool {
+	for _, pattern := range excludes {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(rel)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
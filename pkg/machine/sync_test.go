@@ -0,0 +1,62 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsExcluded(t *testing.T) {
+	excludes := []string{"node_modules", ".git/", "*.log", "build/out"}
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"node_modules", true},
+		{"web/node_modules", true},
+		{".git", true},
+		{"debug.log", true},
+		{"logs/debug.log", true},
+		{"build/out", true},
+		{"build/other", false},
+		{"src/main.go", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isExcluded(tt.rel, excludes), tt.rel)
+	}
+}
+
+func TestParseRsyncStats(t *testing.T) {
+	stats := `
+Number of files: 1,250 (reg: 1,200, dir: 50)
+Number of created files: 3 (reg: 3)
+Number of deleted files: 0
+Number of regular files transferred: 7
+Total file size: 2,345,678 bytes
+`
+	report := parseRsyncStats(stats)
+	assert.Equal(t, 7, report.Transferred)
+	assert.Equal(t, 1193, report.Skipped)
+}
+
+func TestSyncSSHArgs(t *testing.T) {
+	t.Setenv(sshAgentEnv, "")
+	sshConfig := SSHConfig{IdentityPath: "/home/me/.ssh/podman", Port: 2222, RemoteUsername: "core"}
+
+	args, err := sshArgs(sshConfig, SyncOptions{Options: []string{"ProxyJump=bastion"}})
+	assert.NoError(t, err)
+	assert.Equal(t, append(append([]string{"-i", "/home/me/.ssh/podman", "-p", "2222"},
+		HostKeyArgs(sshConfig.IdentityPath, false)...),
+		"-o", "ProxyJump=bastion", "-o", "LogLevel=ERROR"), args)
+	assert.NotContains(t, args, "StrictHostKeyChecking=no")
+
+	args, err = sshArgs(sshConfig, SyncOptions{NoHostKeyCheck: true})
+	assert.NoError(t, err)
+	assert.Contains(t, args, "StrictHostKeyChecking=no")
+
+	_, err = sshArgs(sshConfig, SyncOptions{Options: []string{"Port=22"}})
+	assert.Error(t, err)
+}
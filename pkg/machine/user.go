@@ -0,0 +1,35 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// maxUsernameLength is the longest login name useradd accepts
+const maxUsernameLength = 32
+
+// usernameRegex matches the names useradd accepts without
+// --badname: a lowercase letter or underscore followed by lowercase
+// letters, digits, underscores or dashes, optionally ending in $
+var usernameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_-]*\$?$`)
+
+// ValidateUsername checks that name can be used to create the remote user
+// in the guest
+func ValidateUsername(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("username must not be empty")
+	}
+	if len(name) > maxUsernameLength {
+		return fmt.Errorf("username %q is longer than %d characters", name, maxUsernameLength)
+	}
+	if !usernameRegex.MatchString(name) {
+		return fmt.Errorf("username %q must start with a lowercase letter or underscore and only contain lowercase letters, digits, underscores and dashes", name)
+	}
+	if name == "root" {
+		return fmt.Errorf("username %q is reserved, the remote user is created as an unprivileged user", name)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		wantErr bool
+	}{
+		{name: "default", user: "user"},
+		{name: "digits and dashes", user: "dev-user2"},
+		{name: "leading underscore", user: "_build"},
+		{name: "trailing dollar", user: "host$"},
+		{name: "max length", user: strings.Repeat("a", maxUsernameLength)},
+		{name: "empty", user: "", wantErr: true},
+		{name: "too long", user: strings.Repeat("a", maxUsernameLength+1), wantErr: true},
+		{name: "uppercase", user: "User", wantErr: true},
+		{name: "leading digit", user: "1user", wantErr: true},
+		{name: "leading dash", user: "-user", wantErr: true},
+		{name: "space", user: "my user", wantErr: true},
+		{name: "shell metacharacter", user: "user;reboot", wantErr: true},
+		{name: "root", user: "root", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUsername(tt.user)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
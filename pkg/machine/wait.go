@@ -0,0 +1,84 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrWaitTimeout = errors.New("timed out waiting for machine state")
+
+const (
+	waitInitialInterval = 250 * time.Millisecond
+	waitMaxInterval     = 2 * time.Second
+	// waitMaxErrors is how many times in a row the state may fail to be
+	// queried before waiting is given up
+	waitMaxErrors = 5
+)
+
+// WaitForState polls the state of the machine returned by load, backing off
+// between attempts, until it reaches the desired state. The machine is
+// loaded for every attempt, so waiting ends when it is removed. A timeout of
+// zero waits indefinitely. Transient errors while querying the state are
+// logged and retried, up to waitMaxErrors in a row.
+func WaitForState(load func() (VM, error), desired Status, timeout time.Duration) error {
+	if desired != Running && desired != Stopped {
+		return fmt.Errorf("unsupported wait condition %q, must be %q or %q", desired, Running, Stopped)
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	interval := waitInitialInterval
+	errorCount := 0
+	for {
+		state, err := queryState(load)
+		switch {
+		case errors.Is(err, ErrNoSuchVM):
+			return err
+		case err != nil:
+			errorCount++
+			if errorCount >= waitMaxErrors {
+				return fmt.Errorf("could not query machine state: %w", err)
+			}
+			logrus.Debugf("Could not query machine state: %v", err)
+		// Some providers report an empty state when the machine is not running
+		case state == desired, state == "" && desired == Stopped:
+			return nil
+		default:
+			errorCount = 0
+		}
+
+		sleep := interval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return fmt.Errorf("%w %q after %s", ErrWaitTimeout, desired, timeout)
+			}
+			if sleep > remaining {
+				sleep = remaining
+			}
+		}
+		time.Sleep(sleep)
+
+		interval *= 2
+		if interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
+
+func queryState(load func() (VM, error)) (Status, error) {
+	vm, err := load()
+	if err != nil {
+		return "", err
+	}
+	return vm.State(false)
+}
@@ -0,0 +1,135 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timedVM is a fake VM that reports initial until switchAt has passed, and
+// final afterwards
+type timedVM struct {
+	VM
+	initial  Status
+	final    Status
+	switchAt time.Time
+	polls    int32
+}
+
+func (v *timedVM) State(bool) (Status, error) {
+	atomic.AddInt32(&v.polls, 1)
+	if time.Now().Before(v.switchAt) {
+		return v.initial, nil
+	}
+	return v.final, nil
+}
+
+func TestWaitForState(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial Status
+		final   Status
+		after   time.Duration
+		desired Status
+		timeout time.Duration
+		wantErr error
+	}{
+		{
+			name:    "already running",
+			initial: Running,
+			final:   Running,
+			desired: Running,
+			timeout: time.Second,
+		},
+		{
+			name:    "becomes running",
+			initial: Starting,
+			final:   Running,
+			after:   600 * time.Millisecond,
+			desired: Running,
+			timeout: 5 * time.Second,
+		},
+		{
+			name:    "becomes stopped",
+			initial: Running,
+			final:   Stopped,
+			after:   300 * time.Millisecond,
+			desired: Stopped,
+			timeout: 5 * time.Second,
+		},
+		{
+			name:    "empty state is stopped",
+			initial: Running,
+			final:   "",
+			after:   300 * time.Millisecond,
+			desired: Stopped,
+		},
+		{
+			name:    "timeout",
+			initial: Stopped,
+			final:   Running,
+			after:   time.Hour,
+			desired: Running,
+			timeout: 500 * time.Millisecond,
+			wantErr: ErrWaitTimeout,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &timedVM{initial: tt.initial, final: tt.final, switchAt: time.Now().Add(tt.after)}
+			start := time.Now()
+			err := WaitForState(loadVM(vm), tt.desired, tt.timeout)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+				assert.GreaterOrEqual(t, time.Since(start), tt.timeout)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.after > 0 {
+				assert.GreaterOrEqual(t, time.Since(start), tt.after)
+				assert.Greater(t, atomic.LoadInt32(&vm.polls), int32(1))
+			}
+		})
+	}
+}
+
+func TestWaitForStateInvalidCondition(t *testing.T) {
+	err := WaitForState(loadVM(&timedVM{}), Starting, time.Second)
+	assert.Error(t, err)
+}
+
+// failingVM is a fake VM whose state can never be queried
+type failingVM struct {
+	VM
+	polls int32
+}
+
+func (v *failingVM) State(bool) (Status, error) {
+	atomic.AddInt32(&v.polls, 1)
+	return "", errors.New("query failed")
+}
+
+func TestWaitForStateErrors(t *testing.T) {
+	// Waiting indefinitely still gives up when the state keeps failing
+	vm := &failingVM{}
+	err := WaitForState(loadVM(vm), Running, 0)
+	assert.ErrorContains(t, err, "query failed")
+	assert.Equal(t, int32(waitMaxErrors), atomic.LoadInt32(&vm.polls))
+
+	// A machine removed while waiting ends the wait at once
+	removed := func() (VM, error) { return nil, fmt.Errorf("test: %w", ErrNoSuchVM) }
+	err = WaitForState(removed, Running, 0)
+	assert.ErrorIs(t, err, ErrNoSuchVM)
+}
+
+func loadVM(vm VM) func() (VM, error) {
+	return func() (VM, error) { return vm, nil }
+}
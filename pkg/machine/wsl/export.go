@@ -0,0 +1,136 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// exportFormatVersion is bumped whenever the layout of an exported
+	// machine archive changes incompatibly
+	exportFormatVersion = 1
+	exportManifestName  = "podman-machine.json"
+	exportRootfsName    = "rootfs.tar"
+	exportKeyName       = "id"
+)
+
+var errNotExport = errors.New("not an exported podman machine")
+
+// exportManifest is stored first in an exported machine archive and
+// describes how the machine was configured
+type exportManifest struct {
+	FormatVersion int
+	VMType        string
+	Exported      time.Time
+	Machine       MachineVM
+}
+
+// Export writes the machine, its configuration and its ssh keys into a
+// single tar archive at path, which can be restored with import
+func (v *MachineVM) Export(_ string, path string) error {
+	if v.isRunning() {
+		return fmt.Errorf("running vm %q cannot be exported", v.Name)
+	}
+
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(vmDataDir, "export")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	rootfs := filepath.Join(tmpDir, exportRootfsName)
+	if err := runCmdPassThrough("wsl", "--export", toDist(v.Name), rootfs); err != nil {
+		return fmt.Errorf("the WSL export of guest OS failed: %w", err)
+	}
+
+	manifest, err := json.MarshalIndent(exportManifest{
+		FormatVersion: exportFormatVersion,
+		VMType:        vmtype.String(),
+		Exported:      time.Now(),
+		Machine:       *v,
+	}, "", " ")
+	if err != nil {
+		return err
+	}
+
+	files := [][2]string{
+		{exportKeyName, v.IdentityPath},
+		{exportKeyName + ".pub", v.IdentityPath + ".pub"},
+		{exportRootfsName, rootfs},
+	}
+	if err := writeExportArchive(path, manifest, files); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// writeExportArchive writes the manifest followed by files, pairs of
+// archive name and source path, as a tar archive at path
+func writeExportArchive(path string, manifest []byte, files [][2]string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	tw := tar.NewWriter(out)
+	if err := tw.WriteHeader(&tar.Header{Name: exportManifestName, Mode: 0644, Size: int64(len(manifest)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := addTarFile(tw, file[0], file[1]); err != nil {
+			return fmt.Errorf("could not add %s to the archive: %w", file[1], err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func addTarFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
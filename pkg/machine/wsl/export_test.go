@@ -0,0 +1,72 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestArchive(t *testing.T, manifest exportManifest, names ...string) string {
+	dir := t.TempDir()
+	b, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	var files [][2]string
+	for _, name := range names {
+		src := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(src, []byte(name), 0644))
+		files = append(files, [2]string{name, src})
+	}
+	archive := filepath.Join(dir, "export.tar")
+	require.NoError(t, writeExportArchive(archive, b, files))
+	return archive
+}
+
+func TestExtractExportArchive(t *testing.T) {
+	valid := exportManifest{FormatVersion: exportFormatVersion, VMType: vmtype.String()}
+	valid.Machine.Name = "dev"
+	valid.Machine.Port = 2222
+	valid.Machine.RemoteUsername = "user"
+	allFiles := []string{exportKeyName, exportKeyName + ".pub", exportRootfsName}
+
+	archive := writeTestArchive(t, valid, allFiles...)
+	dest := t.TempDir()
+	manifest, err := extractExportArchive(archive, dest)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", manifest.Machine.Name)
+	assert.Equal(t, 2222, manifest.Machine.Port)
+	assert.Equal(t, "user", manifest.Machine.RemoteUsername)
+	for _, name := range allFiles {
+		b, err := os.ReadFile(filepath.Join(dest, name))
+		require.NoError(t, err)
+		assert.Equal(t, name, string(b))
+	}
+
+	wrongType := valid
+	wrongType.VMType = "qemu"
+	wrongVersion := valid
+	wrongVersion.FormatVersion = exportFormatVersion + 1
+
+	for name, archive := range map[string]string{
+		"missing rootfs":  writeTestArchive(t, valid, exportKeyName, exportKeyName+".pub"),
+		"unexpected file": writeTestArchive(t, valid, append(allFiles, "other")...),
+		"wrong vm type":   writeTestArchive(t, wrongType, allFiles...),
+		"wrong version":   writeTestArchive(t, wrongVersion, allFiles...),
+	} {
+		_, err := extractExportArchive(archive, t.TempDir())
+		assert.Error(t, err, name)
+	}
+
+	notTar := filepath.Join(t.TempDir(), "rootfs.tar")
+	require.NoError(t, os.WriteFile(notTar, []byte("not a tar"), 0644))
+	_, err = extractExportArchive(notTar, t.TempDir())
+	assert.True(t, errors.Is(err, errNotExport))
+}
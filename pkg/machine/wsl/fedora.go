@@ -4,6 +4,8 @@
 package wsl
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -16,11 +18,22 @@ import (
 	"time"
 
 	"github.com/containers/podman/v4/pkg/machine"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
 )
 
 const (
-	githubX86ReleaseURL = "https://github.com/containers/podman-wsl-fedora/releases/latest/download/rootfs.tar.xz"
-	githubArmReleaseURL = "https://github.com/containers/podman-wsl-fedora-arm/releases/latest/download/rootfs.tar.xz"
+	githubURL      = "https://github.com/containers"
+	x86ReleasePath = "podman-wsl-fedora/releases/latest/download/rootfs.tar.xz"
+	armReleasePath = "podman-wsl-fedora-arm/releases/latest/download/rootfs.tar.xz"
+
+	// fedoraMirrorEnv overrides the base URL that Fedora
+	// rootfs releases are downloaded from
+	fedoraMirrorEnv = "PODMAN_MACHINE_FEDORA_MIRROR"
+
+	// checksumSuffix is appended to the image URL to get its published
+	// SHA-256 checksum, and to the cached image to store the checksum of it
+	checksumSuffix = ".sha256"
 )
 
 type FedoraDownload struct {
@@ -42,13 +55,14 @@ func NewFedoraDownloader(vmType machine.VMType, vmName, releaseStream string) (m
 
 	f := FedoraDownload{
 		Download: machine.Download{
-			Arch:      machine.GetFcosArch(),
+			Arch:      fedoraArch(arch),
 			Artifact:  machine.None,
 			CacheDir:  cacheDir,
 			Format:    machine.Tar,
 			ImageName: imageName,
 			LocalPath: filepath.Join(cacheDir, imageName),
 			URL:       downloadURL,
+			Sha256sum: getFedoraChecksum(downloadURL),
 			VMName:    vmName,
 			Size:      size,
 		},
@@ -73,7 +87,44 @@ func (f FedoraDownload) HasUsableCache() (bool, error) {
 		}
 		return false, err
 	}
-	return info.Size() == f.Size, nil
+	// The size misses changes that keep the length of the image, so it is
+	// only compared when no checksum is published
+	if len(f.Sha256sum) == 0 {
+		return info.Size() == f.Size, nil
+	}
+	sum, err := cachedChecksum(f.LocalPath, info)
+	if err != nil {
+		return false, err
+	}
+	return sum == f.Sha256sum, nil
+}
+
+// cachedChecksum returns the SHA-256 checksum of a cached image. The
+// checksum is stored next to the image and recomputed only when the image
+// was modified after it was stored.
+func cachedChecksum(path string, info os.FileInfo) (string, error) {
+	sumPath := path + checksumSuffix
+	if sumInfo, err := os.Stat(sumPath); err == nil && !sumInfo.ModTime().Before(info.ModTime()) {
+		if b, err := os.ReadFile(sumPath); err == nil {
+			if sum := parseChecksum(string(b)); len(sum) > 0 {
+				return sum, nil
+			}
+		}
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	sum, err := digest.SHA256.FromReader(fd)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(sumPath, []byte(sum.Encoded()+"\n"), 0644); err != nil {
+		logrus.Debugf("Could not store the checksum of %s: %v", path, err)
+	}
+	return sum.Encoded(), nil
 }
 
 func (f FedoraDownload) CleanCache() error {
@@ -82,47 +133,148 @@ func (f FedoraDownload) CleanCache() error {
 	return machine.RemoveImageAfterExpire(f.CacheDir, expire)
 }
 
+// fedoraArch maps the host architecture to the name Fedora uses for it.
+// The host architecture comes from the native system, which differs from
+// the architecture of podman itself when it runs emulated on Windows on ARM.
+func fedoraArch(arch string) string {
+	if arch == "arm64" {
+		return "aarch64"
+	}
+	return "x86_64"
+}
+
 func getFedoraDownload() (*url.URL, string, string, int64, error) {
-	var releaseURL string
+	var releasePath string
 	arch := machine.DetermineMachineArch()
 	switch arch {
 	case "arm64":
-		releaseURL = githubArmReleaseURL
+		releasePath = armReleasePath
 	case "amd64":
-		releaseURL = githubX86ReleaseURL
+		releasePath = x86ReleasePath
 	default:
 		return nil, "", "", -1, fmt.Errorf("CPU architecture %q is not supported", arch)
 	}
 
-	downloadURL, err := url.Parse(releaseURL)
+	mirror, err := getFedoraMirror()
 	if err != nil {
-		return nil, "", "", -1, fmt.Errorf("invalid URL generated from discovered Fedora file: %s: %w", releaseURL, err)
+		return nil, "", "", -1, err
 	}
 
-	resp, err := http.Head(releaseURL)
+	downloadURL := *mirror
+	downloadURL.Path = path.Join("/", mirror.Path, releasePath)
+	releaseURL := downloadURL.String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), machine.HTTPTimeout())
+	defer cancel()
+
+	resp, err := httpRequest(ctx, http.MethodHead, releaseURL)
 	if err != nil {
-		return nil, "", "", -1, fmt.Errorf("head request failed: %s: %w", releaseURL, err)
+		return nil, "", "", -1, fmt.Errorf("head request failed: %s: %w", releaseURL, machine.TimeoutError(err))
 	}
 	_ = resp.Body.Close()
 	contentLen := resp.ContentLength
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", "", -1, fmt.Errorf("head request failed: %s: %w", releaseURL, err)
+		return nil, "", "", -1, fmt.Errorf("head request failed: %s: %s", releaseURL, resp.Status)
 	}
 
-	verURL := *downloadURL
+	verURL := downloadURL
 	verURL.Path = path.Join(path.Dir(downloadURL.Path), "version")
 
-	resp, err = http.Get(verURL.String())
+	resp, err = httpRequest(ctx, http.MethodGet, verURL.String())
 	if err != nil {
-		return nil, "", "", -1, fmt.Errorf("get request failed: %s: %w", verURL.String(), err)
+		return nil, "", "", -1, fmt.Errorf("get request failed: %s: %w", verURL.String(), machine.TimeoutError(err))
 	}
 
 	defer resp.Body.Close()
 	bytes, err := io.ReadAll(&io.LimitedReader{R: resp.Body, N: 1024})
 	if err != nil {
-		return nil, "", "", -1, fmt.Errorf("failed reading: %s: %w", verURL.String(), err)
+		return nil, "", "", -1, fmt.Errorf("failed reading: %s: %w", verURL.String(), machine.TimeoutError(err))
 	}
 
-	return downloadURL, strings.TrimSpace(string(bytes)), arch, contentLen, nil
+	return &downloadURL, strings.TrimSpace(string(bytes)), arch, contentLen, nil
+}
+
+// getFedoraChecksum fetches the SHA-256 checksum published next to the
+// image. Releases without one return an empty checksum.
+func getFedoraChecksum(downloadURL *url.URL) string {
+	sumURL := downloadURL.String() + checksumSuffix
+
+	ctx, cancel := context.WithTimeout(context.Background(), machine.HTTPTimeout())
+	defer cancel()
+
+	resp, err := httpRequest(ctx, http.MethodGet, sumURL)
+	if err != nil {
+		logrus.Debugf("Could not fetch the image checksum %s: %v", sumURL, err)
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logrus.Debugf("No image checksum at %s: %s", sumURL, resp.Status)
+		return ""
+	}
+	b, err := io.ReadAll(&io.LimitedReader{R: resp.Body, N: 1024})
+	if err != nil {
+		logrus.Debugf("Could not read the image checksum %s: %v", sumURL, err)
+		return ""
+	}
+	return parseChecksum(string(b))
+}
+
+// parseChecksum accepts a bare SHA-256 hex digest or a line of sha256sum
+// output, and returns the digest in lower case
+func parseChecksum(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	sum := strings.ToLower(fields[0])
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != 64 {
+		return ""
+	}
+	return sum
+}
+
+func httpRequest(ctx context.Context, method string, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return machine.HTTPClient().Do(req)
+}
+
+// getFedoraMirror returns the base URL to download Fedora releases from,
+// honoring an override in the environment
+func getFedoraMirror() (*url.URL, error) {
+	mirror := strings.TrimSpace(os.Getenv(fedoraMirrorEnv))
+	if len(mirror) == 0 {
+		mirror = githubURL
+	}
+
+	mirrorURL, err := url.Parse(mirror)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s URL %q: %w", fedoraMirrorEnv, mirror, err)
+	}
+	if (mirrorURL.Scheme != "http" && mirrorURL.Scheme != "https") || len(mirrorURL.Host) == 0 {
+		return nil, fmt.Errorf("invalid %s URL %q: must be an absolute http or https URL", fedoraMirrorEnv, mirror)
+	}
+
+	return mirrorURL, nil
+}
+
+// isFedoraRelease reports whether an image path names a Fedora release,
+// a release number or "testing", rather than an image file or URL
+func isFedoraRelease(s string) bool {
+	if s == "testing" {
+		return true
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,203 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/containers/storage/pkg/homedir"
+	"github.com/sirupsen/logrus"
+)
+
+// Import restores a machine from an archive written by Export, registering
+// it under name. The machine gets a new ssh port if its original one is
+// in use on this host.
+func (p *Virtualization) Import(name string, path string) (_ machine.VM, err error) {
+	exists, err := p.IsValidVMName(name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%s: %w", name, machine.ErrVMAlreadyExists)
+	}
+	if err := checkDistName(name); err != nil {
+		return nil, err
+	}
+
+	sshDir := filepath.Join(homedir.Get(), ".ssh")
+	identity := filepath.Join(sshDir, name)
+	if _, err := os.Stat(identity); err == nil {
+		return nil, fmt.Errorf("ssh key %q already exists", identity)
+	}
+
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(vmDataDir, "import")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	manifest, err := extractExportArchive(path, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	v := manifest.Machine
+	v.Name = name
+	if v.ConfigPath, err = getConfigPath(name); err != nil {
+		return nil, err
+	}
+	v.IdentityPath = identity
+	// The image the machine was created from stays on the exporting host
+	v.ImagePath = ""
+	if !machine.IsLocalPortAvailable(v.Port) {
+		port, err := machine.AllocateSSHPort()
+		if err != nil {
+			return nil, err
+		}
+		logrus.Warnf("SSH port %d of the exported machine is in use, using port %d instead", v.Port, port)
+		v.Port = port
+	}
+
+	// Undo what was set up so far if a later step fails, so the name can
+	// be imported or initialized again
+	var cleanups []func()
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}()
+
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		return nil, err
+	}
+	cleanups = append(cleanups, func() {
+		for _, file := range []string{identity, identity + ".pub", machine.KnownHostsPath(identity)} {
+			if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+				logrus.Error(err)
+			}
+		}
+	})
+	for _, key := range []string{"", ".pub"} {
+		if err := os.Rename(filepath.Join(tmpDir, exportKeyName+key), identity+key); err != nil {
+			return nil, err
+		}
+	}
+	if err := machine.EnsureKeyPermissions(identity); err != nil {
+		return nil, err
+	}
+
+	distDir := filepath.Join(vmDataDir, "wsldist")
+	if err := os.MkdirAll(distDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create wsldist directory: %w", err)
+	}
+	dist := toDist(name)
+	distTarget := filepath.Join(distDir, name)
+	fmt.Println("Importing operating system into WSL (this may take a few minutes on a new WSL install)...")
+	if err := importDist(false, dist, distTarget, filepath.Join(tmpDir, exportRootfsName)); err != nil {
+		return nil, err
+	}
+	cleanups = append(cleanups, func() { cleanupImport(dist, distTarget) })
+	if v.Port != manifest.Machine.Port {
+		if err := wslInvoke(dist, "sh", "-c", fmt.Sprintf(changePort, v.Port)); err != nil {
+			return nil, fmt.Errorf("could not change the SSH port of the guest OS: %w", err)
+		}
+		_ = terminateDist(dist)
+	}
+
+	if err := v.writeConfig(); err != nil {
+		return nil, err
+	}
+	cleanups = append(cleanups, func() {
+		if err := os.Remove(v.ConfigPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Error(err)
+		}
+	})
+	if err := setupConnections(&v, machine.InitOptions{Rootful: v.Rootful}, sshDir); err != nil {
+		for _, connection := range []string{name, name + "-root"} {
+			_ = machine.RemoveConnection(connection)
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// extractExportArchive validates that path was written by Export and
+// unpacks its files into dir
+func extractExportArchive(path, dir string) (*exportManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != exportManifestName {
+		return nil, fmt.Errorf("%s: %w", path, errNotExport)
+	}
+	manifest := new(exportManifest)
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", path, errNotExport, err)
+	}
+	if manifest.VMType != vmtype.String() {
+		return nil, fmt.Errorf("%s: exported from a %q machine, expected %q", path, manifest.VMType, vmtype.String())
+	}
+	if manifest.FormatVersion != exportFormatVersion {
+		return nil, fmt.Errorf("%s: unsupported export format version %d", path, manifest.FormatVersion)
+	}
+
+	expected := map[string]bool{exportKeyName: false, exportKeyName + ".pub": false, exportRootfsName: false}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := expected[hdr.Name]; !ok {
+			return nil, fmt.Errorf("%s: unexpected file %q: %w", path, hdr.Name, errNotExport)
+		}
+		if err := extractTarFile(tr, filepath.Join(dir, hdr.Name)); err != nil {
+			return nil, err
+		}
+		expected[hdr.Name] = true
+	}
+	for name, found := range expected {
+		if !found {
+			return nil, fmt.Errorf("%s: missing %q: %w", path, name, errNotExport)
+		}
+	}
+	return manifest, nil
+}
+
+func extractTarFile(r io.Reader, dest string) error {
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -14,15 +14,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/containers/common/pkg/config"
 	"github.com/containers/podman/v4/pkg/machine"
-	"github.com/containers/podman/v4/utils"
 	"github.com/containers/storage/pkg/homedir"
+	"github.com/hashicorp/go-multierror"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
 	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
 )
@@ -32,6 +34,15 @@ var (
 	vmtype = machine.WSLVirt
 )
 
+const (
+	// readyTimeout bounds how long start waits for the guest to come up
+	readyTimeout  = 90 * time.Second
+	readyInterval = 500 * time.Millisecond
+)
+
+// defaultRemoteUser is the guest user created when no username is given
+const defaultRemoteUser = "user"
+
 const (
 	ErrorSuccessRebootInitiated = 1641
 	ErrorSuccessRebootRequired  = 3010
@@ -49,6 +60,8 @@ const registriesConf = `unqualified-search-registries=["docker.io"]
 
 const appendPort = `grep -q Port\ %d /etc/ssh/sshd_config || echo Port %d >> /etc/ssh/sshd_config`
 
+const changePort = `sed -E -i 's/^Port[[:space:]]+[0-9]+$/Port %d/' /etc/ssh/sshd_config`
+
 const configServices = `ln -fs /usr/lib/systemd/system/sshd.service /etc/systemd/system/multi-user.target.wants/sshd.service
 ln -fs /usr/lib/systemd/system/podman.socket /etc/systemd/system/sockets.target.wants/podman.socket
 rm -f /etc/systemd/system/getty.target.wants/console-getty.service
@@ -67,9 +80,18 @@ chown [USER]:[USER] /home/[USER]/.config
 const sudoers = `%wheel        ALL=(ALL)       NOPASSWD: ALL
 `
 
+// systemdPath is the systemd manager bootstrap starts in its own pid namespace
+const systemdPath = "/lib/systemd/systemd"
+
+// systemdPidCmd prints the pid of the systemd manager, if it is running. The
+// executable must match exactly, so services such as systemd-journald and the
+// unshare that started the manager are not taken for it. systemdPid parses
+// the same ps output.
+const systemdPidCmd = `ps -eo pid=,args= | awk '$2 == "` + systemdPath + `" { print $1; exit }'`
+
 const bootstrap = `#!/bin/bash
-ps -ef | grep -v grep | grep -q systemd && exit 0
-nohup unshare --kill-child --fork --pid --mount --mount-proc --propagation shared /lib/systemd/systemd >/dev/null 2>&1 &
+[ -n "$(` + systemdPidCmd + `)" ] && exit 0
+nohup unshare --kill-child --fork --pid --mount --mount-proc --propagation shared ` + systemdPath + ` >/dev/null 2>&1 &
 sleep 0.1
 `
 
@@ -80,33 +102,60 @@ or type exit. This also means to log out you need to exit twice.
 
 `
 
-const sysdpid = "SYSDPID=`ps -eo cmd,pid | grep -m 1 ^/lib/systemd/systemd | awk '{print $2}'`"
+// sysdpid sets SYSDPID to the pid of the systemd manager when it runs in a
+// namespace of its own, which enterns enters. It is left empty both when
+// systemd is not running and when it is pid 1, as there is no namespace to
+// enter then and commands run in place.
+const sysdpid = "SYSDPID=`" + systemdPidCmd + "`" + `
+if [ "$SYSDPID" = "1" ]; then
+    SYSDPID=
+fi`
+
+// noEnternsEnv keeps interactive shells in the root namespace, without the
+// MOTD. Set on the host during init, it provisions the machine that way by
+// creating noEnternsFile; set in a shell of the distribution, for instance
+// shared through WSLENV, it applies to that shell.
+const (
+	noEnternsEnv  = "PODMAN_MACHINE_NO_ENTERNS"
+	noEnternsFile = "/etc/podman-machine-no-enterns"
+)
 
 const profile = sysdpid + `
-if [ ! -z "$SYSDPID" ] && [ "$SYSDPID" != "1" ]; then
+case "$` + noEnternsEnv + `" in
+    1|[tT]rue|TRUE) NOENTERNS=1 ;;
+    *) [ -e ` + noEnternsFile + ` ] && NOENTERNS=1 ;;
+esac
+if [ -z "$NOENTERNS" ] && [ -n "$SYSDPID" ]; then
     cat /etc/wslmotd
 	/usr/local/bin/enterns
 fi
+unset NOENTERNS
 `
 
 const enterns = "#!/bin/bash\n" + sysdpid + `
-if [ ! -z "$SYSDPID" ] && [ "$SYSDPID" != "1" ]; then
-        NSENTER=("nsenter" "-m" "-p" "-t" "$SYSDPID" "--wd=$PWD")
-
-        if [ "$UID" != "0" ]; then
-                NSENTER=("sudo" "${NSENTER[@]}")
-                if [ "$#" != "0" ]; then
-                        NSENTER+=("sudo" "-u" "$USER")
-                else
-                        NSENTER+=("su" "-l" "$USER")
-                fi
+if [ -z "$SYSDPID" ]; then
+        # Nothing to enter, run the command in place
+        if [ "$#" != "0" ]; then
+                exec "$@"
         fi
-        "${NSENTER[@]}" "$@"
-fi`
+        exit 0
+fi
+NSENTER=("nsenter" "-m" "-p" "-t" "$SYSDPID" "--wd=$PWD")
+
+if [ "$UID" != "0" ]; then
+        NSENTER=("sudo" "${NSENTER[@]}")
+        if [ "$#" != "0" ]; then
+                NSENTER+=("sudo" "-u" "$USER")
+        else
+                NSENTER+=("su" "-l" "$USER")
+        fi
+fi
+"${NSENTER[@]}" "$@"
+`
 
 const waitTerm = sysdpid + `
-if [ ! -z "$SYSDPID" ]; then
-	timeout 60 tail -f /dev/null --pid $SYSDPID
+if [ -n "$SYSDPID" ]; then
+	timeout %d tail -f /dev/null --pid $SYSDPID
 fi
 `
 
@@ -201,6 +250,12 @@ const (
 	winSshProxyTid = "win-sshproxy.tid"
 	pipePrefix     = "npipe:////./pipe/"
 	globalPipe     = "docker_engine"
+	// winProxyAttempts bounds how often the api proxy is launched when it
+	// does not stay up after start
+	winProxyAttempts = 3
+	// winProxySettle is how long the api proxy has to stay up to be
+	// considered healthy
+	winProxySettle = time.Second
 )
 
 type Virtualization struct {
@@ -222,6 +277,8 @@ func (p *Virtualization) Format() machine.ImageFormat {
 }
 
 type MachineVM struct {
+	// CPUs is the number of processors last seen in the guest
+	CPUs uint64 `json:",omitempty"`
 	// ConfigPath is the path to the configuration file
 	ConfigPath string
 	// Created contains the original created time instead of querying the file mod time
@@ -232,6 +289,10 @@ type MachineVM struct {
 	ImagePath string
 	// LastUp contains the last recorded uptime
 	LastUp time.Time
+	// Memory is the total memory, in bytes, last seen in the guest
+	Memory uint64 `json:",omitempty"`
+	// Mounts is the list of host directories bind mounted into the guest
+	Mounts []machine.Mount
 	// Name of the vm
 	Name string
 	// Whether this machine should run in a rootful or rootless manner
@@ -250,6 +311,12 @@ func (e *ExitCodeError) Error() string {
 	return fmt.Sprintf("Process failed with exit code: %d", e.code)
 }
 
+// Is lets an elevated process that exited asking for a reboot match
+// machine.ErrRebootRequired
+func (e *ExitCodeError) Is(target error) bool {
+	return target == machine.ErrRebootRequired && e.code == ErrorSuccessRebootRequired
+}
+
 func GetWSLProvider() machine.VirtProvider {
 	return &Virtualization{
 		artifact:    machine.None,
@@ -272,11 +339,17 @@ func (p *Virtualization) NewMachine(opts machine.InitOptions) (machine.VM, error
 	vm.ConfigPath = configPath
 	vm.ImagePath = opts.ImagePath
 	vm.RemoteUsername = opts.Username
+	if len(vm.RemoteUsername) == 0 {
+		vm.RemoteUsername = defaultRemoteUser
+	}
+	if err := machine.ValidateUsername(vm.RemoteUsername); err != nil {
+		return nil, err
+	}
 	vm.Created = time.Now()
 	vm.LastUp = vm.Created
 
 	// Add a random port for ssh
-	port, err := utils.GetRandomPort()
+	port, err := machine.AllocateSSHPort()
 	if err != nil {
 		return nil, err
 	}
@@ -301,6 +374,9 @@ func getConfigPathExt(name string, extension string) (string, error) {
 // LoadByName reads a json file that describes a known qemu vm
 // and returns a vm instance
 func (p *Virtualization) LoadVMByName(name string) (machine.VM, error) {
+	if err := checkWSLExecutable(); err != nil {
+		return nil, err
+	}
 	configPath, err := getConfigPath(name)
 	if err != nil {
 		return nil, err
@@ -325,6 +401,13 @@ func readAndMigrate(configPath string, name string) (*MachineVM, error) {
 	if err == nil && vm.Version < currentMachineVersion {
 		err = vm.migrateMachine(configPath)
 	}
+	if err == nil && vm.Created.IsZero() {
+		// The config is current but lacks the creation time, as when
+		// it was edited by hand or imported without one
+		if err = vm.migrate40(configPath); err == nil {
+			err = vm.writeConfig()
+		}
+	}
 
 	return vm, err
 }
@@ -390,28 +473,75 @@ func (v *MachineVM) Init(opts machine.InitOptions) (bool, error) {
 	v.Rootful = opts.Rootful
 	v.Version = currentMachineVersion
 
+	mounts, err := volumesToMounts(opts.Volumes)
+	if err != nil {
+		return false, err
+	}
+	v.Mounts = mounts
+
+	// Check the custom wsl.conf before anything is downloaded
+	extraConf, err := readWSLConf(opts.WSLConfPath)
+	if err != nil {
+		return false, err
+	}
+	dnsWSLConf, resolvConf, err := dnsConf(opts.DNSServers, opts.DNSSearch)
+	if err != nil {
+		return false, err
+	}
+	extraConf = dnsWSLConf + extraConf
+	tz, err := guestTimeZone(opts.TimeZone)
+	if err != nil {
+		return false, err
+	}
+	if len(tz) > 0 {
+		extraConf = timeZoneWSLConf + extraConf
+	}
+
+	if err := checkDistName(v.Name); err != nil {
+		return false, err
+	}
+	if err := removeStaleDist(v.Name, opts.Force); err != nil {
+		return false, err
+	}
+
 	if err := downloadDistro(v, opts); err != nil {
 		return false, err
 	}
 
-	dist, err := provisionWSLDist(v)
+	dist, err := provisionWSLDist(v, opts.Quiet)
 	if err != nil {
 		return false, err
 	}
 
-	fmt.Println("Configuring system...")
-	if err = configureSystem(v, dist); err != nil {
+	if !opts.Quiet {
+		fmt.Println("Configuring system...")
+	}
+	if err = configureSystem(v, dist, extraConf); err != nil {
 		return false, err
 	}
 
+	if len(resolvConf) > 0 {
+		if err := wslPipe(resolvConf, dist, "sh", "-c", resolvConfSetup); err != nil {
+			return false, fmt.Errorf("could not configure DNS for guest OS: %w", err)
+		}
+	}
+
+	if len(tz) > 0 {
+		if err := configureTimeZone(dist, tz); err != nil {
+			return false, err
+		}
+	}
+
 	if err = installScripts(dist); err != nil {
 		return false, err
 	}
 
-	if err = createKeys(v, dist, sshDir); err != nil {
+	if err = createKeys(v, dist, sshDir, opts.Quiet); err != nil {
 		return false, err
 	}
 
+	v.recordResources()
+
 	// Cycle so that user change goes into effect
 	_ = terminateDist(dist)
 
@@ -427,24 +557,153 @@ func (v *MachineVM) Init(opts machine.InitOptions) (bool, error) {
 }
 
 func downloadDistro(v *MachineVM, opts machine.InitOptions) error {
-	var (
-		dd  machine.DistributionDownload
-		err error
-	)
+	dd, err := newDistroDownloader(v, opts)
+	if err != nil {
+		return err
+	}
+
+	v.ImagePath = dd.Get().LocalUncompressedFile
+	if err := machine.DownloadImage(dd, opts.Quiet); err != nil {
+		return err
+	}
+	// An image from a URL can only be checked once it is downloaded
+	if _, ok := dd.(URLDownload); ok {
+		return checkRootfsFormat(dd.Get().LocalPath)
+	}
+	return nil
+}
+
+// newDistroDownloader resolves the image of a new machine without
+// downloading it
+func newDistroDownloader(v *MachineVM, opts machine.InitOptions) (machine.DistributionDownload, error) {
+	imageURL, err := parseImageURL(opts.ImagePath)
+	if err != nil {
+		return nil, err
+	}
 
-	if _, e := strconv.Atoi(opts.ImagePath); e == nil {
+	switch {
+	case isFedoraRelease(opts.ImagePath):
+		if opts.Offline {
+			return nil, fmt.Errorf("--offline requires --image-path to point to a local image, %q is a Fedora release to download", opts.ImagePath)
+		}
 		v.ImageStream = opts.ImagePath
-		dd, err = NewFedoraDownloader(vmtype, v.Name, opts.ImagePath)
-	} else {
+		return NewFedoraDownloader(vmtype, v.Name, opts.ImagePath)
+	case imageURL != nil:
+		if opts.Offline {
+			return nil, fmt.Errorf("--offline requires --image-path to point to a local image, not %s", opts.ImagePath)
+		}
 		v.ImageStream = "custom"
-		dd, err = machine.NewGenericDownloader(vmtype, v.Name, opts.ImagePath)
+		return NewURLDownloader(vmtype, v.Name, imageURL)
+	}
+
+	v.ImageStream = "custom"
+	dd, err := machine.NewGenericDownloader(vmtype, v.Name, opts.ImagePath)
+	if err != nil {
+		return nil, err
+	}
+	// Catch disk images meant for other providers before wsl --import
+	// fails on them with an obscure error
+	if err := checkRootfsFormat(opts.ImagePath); err != nil {
+		return nil, err
+	}
+	return dd, nil
+}
+
+// PlanInit reports what Init would do with the given options. It runs the
+// same checks as Init but neither installs WSL nor downloads or imports
+// the image.
+func (v *MachineVM) PlanInit(opts machine.InitOptions) (*machine.InitPlan, error) {
+	if wslDisabledByPolicy() {
+		return nil, fmt.Errorf("WSL is disabled by group policy on this system: %w", machine.ErrFeatureDisabledByPolicy)
+	}
+	if _, err := volumesToMounts(opts.Volumes); err != nil {
+		return nil, err
+	}
+	if _, err := readWSLConf(opts.WSLConfPath); err != nil {
+		return nil, err
+	}
+	if _, _, err := dnsConf(opts.DNSServers, opts.DNSSearch); err != nil {
+		return nil, err
+	}
+
+	install := wslInstallNeeded()
+	// Distributions can only be listed once WSL is installed
+	if len(install) == 0 {
+		if err := checkDistName(v.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	dd, err := newDistroDownloader(v, opts)
+	if err != nil {
+		return nil, err
+	}
+	image := opts.ImagePath
+	if u := dd.Get().URL; u != nil {
+		image = u.String()
 	}
+	cached, err := dd.HasUsableCache()
+	if err != nil {
+		return nil, err
+	}
+
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return nil, err
+	}
+
+	return &machine.InitPlan{
+		Image:        image,
+		ImageCached:  cached,
+		Distribution: toDist(v.Name),
+		SSHPort:      v.Port,
+		ConfigPath:   v.ConfigPath,
+		DataDir:      filepath.Join(vmDataDir, "wsldist", v.Name),
+		Install:      install,
+	}, nil
+}
+
+// wslInstallNeeded reports which part of WSL checkAndInstallWSL would
+// install. Unlike IsWSLFeatureEnabled it does not change the WSL
+// configuration.
+func wslInstallNeeded() string {
+	if checkWSLExecutable() != nil {
+		return "WSL feature"
+	}
+	status, err := GetWSLStatus()
+	if err != nil {
+		return "WSL feature"
+	}
+	if status.KernelMissing {
+		return "WSL kernel"
+	}
+	return ""
+}
+
+// reassignPortIfInUse moves the machine to a new ssh port when another
+// process claimed the stored one while the machine was stopped. The guest
+// sshd configuration, the system connections and the machine config are
+// all updated to the new port.
+func (v *MachineVM) reassignPortIfInUse(dist string) error {
+	if machine.IsLocalPortAvailable(v.Port) {
+		return nil
+	}
+
+	port, err := machine.AllocateSSHPort()
 	if err != nil {
 		return err
 	}
+	logrus.Warnf("SSH port %d of machine %q is in use by another process, reassigning to port %d", v.Port, v.Name, port)
 
-	v.ImagePath = dd.Get().LocalUncompressedFile
-	return machine.DownloadImage(dd)
+	if err := wslInvoke(dist, "sh", "-c", fmt.Sprintf(changePort, port)); err != nil {
+		return fmt.Errorf("could not change the SSH port of the guest OS: %w", err)
+	}
+	v.Port = port
+
+	if err := machine.ChangeConnectionPort(port, v.Name, v.Name+"-root"); err != nil {
+		return fmt.Errorf("could not update system connections with the new SSH port: %w", err)
+	}
+	return v.writeConfig()
 }
 
 func (v *MachineVM) writeConfig() error {
@@ -494,7 +753,7 @@ func setupConnections(v *MachineVM, opts machine.InitOptions, sshDir string) err
 	return nil
 }
 
-func provisionWSLDist(v *MachineVM) (string, error) {
+func provisionWSLDist(v *MachineVM, quiet bool) (string, error) {
 	vmDataDir, err := machine.GetDataDir(vmtype)
 	if err != nil {
 		return "", err
@@ -507,9 +766,38 @@ func provisionWSLDist(v *MachineVM) (string, error) {
 	}
 
 	dist := toDist(v.Name)
-	fmt.Println("Importing operating system into WSL (this may take a few minutes on a new WSL install)...")
-	if err = runCmdPassThrough("wsl", "--import", dist, distTarget, v.ImagePath, "--version", "2"); err != nil {
-		return "", fmt.Errorf("the WSL import of guest OS failed: %w", err)
+	if !quiet {
+		fmt.Println("Importing operating system into WSL (this may take a few minutes on a new WSL install)...")
+	}
+	if err = importDist(quiet, dist, distTarget, v.ImagePath); err != nil {
+		return "", err
+	}
+
+	// An image built for another architecture imports fine but can not run
+	// anything, so catch it before provisioning fails in obscure ways
+	if err = exec.Command("wsl", "-u", "root", "-d", dist, "/bin/true").Run(); err != nil {
+		if unregErr := exec.Command("wsl", "--unregister", dist).Run(); unregErr != nil {
+			logrus.Warnf("could not unregister %s: %v", dist, unregErr)
+		}
+		return "", fmt.Errorf("the guest OS image %s can not run on this %s host, it may be built for another architecture: %w",
+			v.ImagePath, machine.DetermineMachineArch(), err)
+	}
+
+	// Nothing is installed into the guest during provisioning, so an image
+	// lacking a required command would otherwise fail halfway through
+	if missing := missingGuestCommands(dist); len(missing) > 0 {
+		if unregErr := exec.Command("wsl", "--unregister", dist).Run(); unregErr != nil {
+			logrus.Warnf("could not unregister %s: %v", dist, unregErr)
+		}
+		return "", fmt.Errorf("the guest OS image %s is missing commands required by podman machine: %s",
+			v.ImagePath, strings.Join(missing, ", "))
+	}
+
+	// A freshly imported dist can start with a skewed clock (notably after a
+	// host resume), which breaks signature and certificate checks performed
+	// by package operations
+	if err = syncGuestClock(dist); err != nil {
+		logrus.Warnf("could not sync the guest clock with the host: %s", err.Error())
 	}
 
 	// Fixes newuidmap
@@ -529,7 +817,123 @@ func provisionWSLDist(v *MachineVM) (string, error) {
 	return dist, nil
 }
 
-func createKeys(v *MachineVM, dist string, sshDir string) error {
+// requiredGuestCommands must be present in the guest OS image, since
+// provisioning configures but does not install them
+var requiredGuestCommands = []string{"podman", "sshd", "systemctl", "adduser", "rpm"}
+
+// missingGuestCommands returns the required commands not found in the guest
+func missingGuestCommands(dist string) []string {
+	script := fmt.Sprintf("for c in %s; do command -v $c > /dev/null || echo $c; done", strings.Join(requiredGuestCommands, " "))
+	out, err := exec.Command("wsl", "-u", "root", "-d", dist, "sh", "-c", script).Output()
+	if err != nil {
+		logrus.Warnf("could not check the guest OS for required commands: %v", err)
+		return nil
+	}
+	return strings.Fields(string(out))
+}
+
+// checkDistName returns an error if the WSL distribution of a new machine
+// called name is taken by something else. toDist keeps names starting
+// with podman as they are, so machines "foo" and "podman-foo" would share
+// a distribution, and a name such as "podman" could shadow a distribution
+// created outside of podman machine.
+func checkDistName(name string) error {
+	dist := toDist(name)
+	infos, err := GetVMInfos()
+	if err != nil {
+		return err
+	}
+	if other := distOwner(name, infos); len(other) > 0 {
+		return fmt.Errorf("machine name %q can not be used, its WSL distribution %s belongs to machine %q", name, dist, other)
+	}
+
+	exists, err := isWSLExist(dist)
+	if err != nil || !exists {
+		return nil
+	}
+	// Distributions imported by podman machine are stored under wsldist,
+	// including those left behind by an interrupted init
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(vmDataDir, "wsldist", name)); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("machine name %q can not be used, it would shadow the WSL distribution %s, which is not managed by podman machine", name, dist)
+	}
+	return nil
+}
+
+// distOwner returns the name of another machine using the WSL
+// distribution of a machine called name
+func distOwner(name string, machines []*machine.ListResponse) string {
+	for _, vm := range machines {
+		if vm.Name != name && strings.EqualFold(toDist(vm.Name), toDist(name)) {
+			return vm.Name
+		}
+	}
+	return ""
+}
+
+// removeStaleDist cleans up what an interrupted init of the named machine
+// left behind. A registered distribution is unregistered when force is
+// set or the user agrees to it, and a leftover wsldist directory, which
+// would make wsl --import fail, is removed.
+func removeStaleDist(name string, force bool) error {
+	dist := toDist(name)
+	exists, err := isWSLExist(dist)
+	if err != nil {
+		logrus.Debugf("Could not list WSL distributions: %v", err)
+		return nil
+	}
+	if exists {
+		if !force && !confirmStaleDistRemoval(dist) {
+			return fmt.Errorf("a WSL distribution named %s already exists, probably left behind by an interrupted machine init: "+
+				"rerun with --force to unregister it, or remove it with 'wsl --unregister %s'", dist, dist)
+		}
+		logrus.Warnf("Unregistering the existing WSL distribution %s", dist)
+		if err := exec.Command("wsl", "--terminate", dist).Run(); err != nil {
+			logrus.Debugf("Could not terminate %s: %v", dist, err)
+		}
+		if err := exec.Command("wsl", "--unregister", dist).Run(); err != nil {
+			return fmt.Errorf("could not unregister the existing WSL distribution %s: %w", dist, err)
+		}
+	}
+
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return err
+	}
+	distTarget := filepath.Join(vmDataDir, "wsldist", name)
+	if _, err := os.Stat(distTarget); err == nil {
+		logrus.Debugf("Removing the leftover distribution directory %s", distTarget)
+		if err := os.RemoveAll(distTarget); err != nil {
+			return fmt.Errorf("could not remove the leftover distribution directory %s: %w", distTarget, err)
+		}
+	}
+	return nil
+}
+
+// confirmStaleDistRemoval asks whether a distribution left behind by an
+// interrupted init should be unregistered, when running interactively
+func confirmStaleDistRemoval(dist string) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	fmt.Printf("The WSL distribution %s has no machine configuration, it was probably left behind by an interrupted machine init.\n", dist)
+	fmt.Print("Unregister it and continue? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y")
+}
+
+// syncGuestClock sets the guest clock to the current host time
+func syncGuestClock(dist string) error {
+	return wslInvoke(dist, "sh", "-c", fmt.Sprintf("date -u -s @%d > /dev/null", time.Now().Unix()))
+}
+
+func createKeys(v *MachineVM, dist string, sshDir string, quiet bool) error {
 	user := v.RemoteUsername
 
 	if err := os.MkdirAll(sshDir, 0700); err != nil {
@@ -540,7 +944,7 @@ func createKeys(v *MachineVM, dist string, sshDir string) error {
 		return fmt.Errorf("could not cycle WSL dist: %w", err)
 	}
 
-	key, err := wslCreateKeys(sshDir, v.Name, dist)
+	key, err := wslCreateKeys(sshDir, v.Name, dist, quiet)
 	if err != nil {
 		return fmt.Errorf("could not create ssh keys: %w", err)
 	}
@@ -560,7 +964,7 @@ func createKeys(v *MachineVM, dist string, sshDir string) error {
 	return nil
 }
 
-func configureSystem(v *MachineVM, dist string) error {
+func configureSystem(v *MachineVM, dist string, extraConf string) error {
 	user := v.RemoteUsername
 	if err := wslInvoke(dist, "sh", "-c", fmt.Sprintf(appendPort, v.Port, v.Port)); err != nil {
 		return fmt.Errorf("could not configure SSH port for guest OS: %w", err)
@@ -604,7 +1008,11 @@ func configureSystem(v *MachineVM, dist string) error {
 		return fmt.Errorf("could not create podman-machine file for guest OS: %w", err)
 	}
 
-	if err := wslPipe(withUser(wslConf, user), dist, "sh", "-c", "cat > /etc/wsl.conf"); err != nil {
+	conf := withUser(wslConf, user)
+	if len(extraConf) > 0 {
+		conf += "\n" + extraConf
+	}
+	if err := wslPipe(conf, dist, "sh", "-c", "cat > /etc/wsl.conf"); err != nil {
 		return fmt.Errorf("could not configure wsl config for guest OS: %w", err)
 	}
 
@@ -679,6 +1087,12 @@ func installScripts(dist string) error {
 		return fmt.Errorf("could not create a WSL MOTD for guest OS: %w", err)
 	}
 
+	if skipEnterns() {
+		if err := wslInvoke(dist, "touch", noEnternsFile); err != nil {
+			return fmt.Errorf("could not disable entering the systemd namespace on guest OS: %w", err)
+		}
+	}
+
 	if err := wslPipe(bootstrap, dist, "sh", "-c",
 		"cat > /root/bootstrap; chmod 755 /root/bootstrap"); err != nil {
 		return fmt.Errorf("could not create bootstrap script for guest OS: %w", err)
@@ -693,6 +1107,12 @@ func installScripts(dist string) error {
 }
 
 func checkAndInstallWSL(opts machine.InitOptions) (bool, error) {
+	// Installing or relaunching elevated can never succeed when WSL is
+	// disabled by policy
+	if wslDisabledByPolicy() {
+		return false, fmt.Errorf("WSL is disabled by group policy on this system: %w", machine.ErrFeatureDisabledByPolicy)
+	}
+
 	if IsWSLInstalled() {
 		return true, nil
 	}
@@ -705,7 +1125,9 @@ func checkAndInstallWSL(opts machine.InitOptions) (bool, error) {
 
 	skip := false
 	if !opts.ReExec && !admin {
-		fmt.Println("Launching WSL Kernel Install...")
+		if !opts.Quiet {
+			fmt.Println("Launching WSL Kernel Install...")
+		}
 		if err := launchElevate(wslInstallKernel); err != nil {
 			return false, err
 		}
@@ -752,28 +1174,68 @@ func attemptFeatureInstall(opts machine.InitOptions, admin bool) error {
 	if !opts.ReExec && !admin {
 		return launchElevate("install the Windows WSL Features")
 	}
+	if !admin {
+		return fmt.Errorf("could not install the Windows WSL Features: %w", machine.ErrAdminRequired)
+	}
 
 	return installWsl()
 }
 
 func launchElevate(operation string) error {
 	truncateElevatedOutputFile()
+	stopTail := tailElevatedOutput()
 	err := relaunchElevatedWait()
+	tailed := stopTail()
 	if err != nil {
-		if eerr, ok := err.(*ExitCodeError); ok {
-			if eerr.code == ErrorSuccessRebootRequired {
-				fmt.Println("Reboot is required to continue installation, please reboot at your convenience")
-				return nil
-			}
+		if errors.Is(err, machine.ErrRebootRequired) {
+			fmt.Println("Reboot is required to continue installation, please reboot at your convenience")
+			return nil
 		}
 
 		fmt.Fprintf(os.Stderr, "Elevated process failed with error: %v\n\n", err)
-		dumpOutputFile()
+		// The output was already shown while the process ran
+		if !tailed {
+			dumpOutputFile()
+		}
 		fmt.Fprintf(os.Stderr, wslInstallError, operation)
 	}
 	return err
 }
 
+// tailElevatedOutput copies the output of the elevated child to stdout as it
+// is written, so progress of long operations is visible in this window. The
+// returned function stops tailing after copying any remaining output, and
+// reports whether the output could be tailed at all.
+func tailElevatedOutput() func() bool {
+	file, err := getElevatedOutputFile(os.O_RDONLY | os.O_CREATE)
+	if err != nil {
+		logrus.Debugf("Could not tail elevated child output: %v", err)
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			_, _ = io.Copy(os.Stdout, file)
+			select {
+			case <-done:
+				_, _ = io.Copy(os.Stdout, file)
+				return
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		<-finished
+		file.Close()
+		return true
+	}
+}
+
 func installWsl() error {
 	log, err := getElevatedOutputFileWrite()
 	if err != nil {
@@ -932,8 +1394,8 @@ func wslPipe(input string, dist string, arg ...string) error {
 	return pipeCmdPassThrough("wsl", input, newArgs...)
 }
 
-func wslCreateKeys(sshDir string, name string, dist string) (string, error) {
-	return machine.CreateSSHKeysPrefix(sshDir, name, true, true, "wsl", "-u", "root", "-d", dist)
+func wslCreateKeys(sshDir string, name string, dist string, quiet bool) (string, error) {
+	return machine.CreateSSHKeysPrefix(sshDir, name, !quiet, true, "wsl", "-u", "root", "-d", dist)
 }
 
 func runCmdPassThrough(name string, arg ...string) error {
@@ -945,6 +1407,20 @@ func runCmdPassThrough(name string, arg ...string) error {
 	return cmd.Run()
 }
 
+// runCmdProgress runs a command whose output only reports progress, which
+// is dropped when quiet. Errors are still written to stderr.
+func runCmdProgress(quiet bool, name string, arg ...string) error {
+	logrus.Debugf("Running command: %s %v", name, arg)
+	cmd := exec.Command(name, arg...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	if quiet {
+		cmd.Stdout = io.Discard
+	}
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 func runCmdPassThroughTee(out io.Writer, name string, arg ...string) error {
 	logrus.Debugf("Running command: %s %v", name, arg)
 
@@ -966,6 +1442,21 @@ func pipeCmdPassThrough(name string, input string, arg ...string) error {
 	return cmd.Run()
 }
 
+// skipEnterns reports whether PODMAN_MACHINE_NO_ENTERNS asks for the machine
+// to be provisioned without entering the systemd namespace on login
+func skipEnterns() bool {
+	value, found := os.LookupEnv(noEnternsEnv)
+	if !found || len(value) == 0 {
+		return false
+	}
+	skip, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.Warnf("Ignoring invalid %s value %q", noEnternsEnv, value)
+		return false
+	}
+	return skip
+}
+
 func setupWslProxyEnv() (hasProxy bool) {
 	current, _ := os.LookupEnv("WSLENV")
 	for _, key := range config.ProxyEnv {
@@ -997,6 +1488,13 @@ func (v *MachineVM) Set(_ string, opts machine.SetOptions) ([]error, error) {
 			setErrors = append(setErrors, fmt.Errorf("setting rootful option: %w", err))
 		} else {
 			v.Rootful = *opts.Rootful
+			if v.isRunning() {
+				suffix := ""
+				if v.Name != machine.DefaultMachineName {
+					suffix = " " + v.Name
+				}
+				logrus.Warnf("The rootful setting takes effect once the machine is restarted, run 'podman machine stop%s' and 'podman machine start%s'", suffix, suffix)
+			}
 		}
 	}
 
@@ -1021,17 +1519,35 @@ func (v *MachineVM) Start(name string, opts machine.StartOptions) error {
 		return fmt.Errorf("%q is already running", name)
 	}
 
+	// Keys created before permissions were enforced, or altered since, are
+	// rejected by ssh when readable by other users
+	if err := machine.EnsureKeyPermissions(v.IdentityPath); err != nil {
+		logrus.Warn(err.Error())
+	}
+
 	dist := toDist(name)
+	if err := v.reassignPortIfInUse(dist); err != nil {
+		return err
+	}
+
 	useProxy := setupWslProxyEnv()
 	if err := configureProxy(dist, useProxy, opts.Quiet); err != nil {
 		return err
 	}
 
-	err := wslInvoke(dist, "/root/bootstrap")
+	err := runCmdProgress(opts.Quiet, "wsl", "-u", "root", "-d", dist, "/root/bootstrap")
 	if err != nil {
 		return fmt.Errorf("the WSL bootstrap script failed: %w", err)
 	}
 
+	if err := waitForReady(dist, v.Rootful); err != nil {
+		return err
+	}
+
+	if err := mountVolumes(v, dist, opts.Quiet); err != nil {
+		return err
+	}
+
 	if !v.Rootful && !opts.NoInfo {
 		fmt.Printf("\nThis machine is currently configured in rootless mode. If your containers\n")
 		fmt.Printf("require root permissions (e.g. ports < 1024), or if you run into compatibility\n")
@@ -1044,7 +1560,12 @@ func (v *MachineVM) Start(name string, opts machine.StartOptions) error {
 		fmt.Printf("\n\tpodman machine set --rootful%s\n\n", suffix)
 	}
 
-	globalName, pipeName, err := launchWinProxy(v)
+	// A proxy left running by an unclean shutdown holds the pipes
+	if err := stopWinProxy(v); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logrus.Warnf("Could not stop leftover API forwarding service (win-sshproxy.exe): %v", err)
+	}
+
+	globalName, pipeName, err := launchHealthyWinProxy(v)
 	if !opts.NoInfo {
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "API forwarding for Docker API clients is not available due to the following startup failures.")
@@ -1066,6 +1587,7 @@ func (v *MachineVM) Start(name string, opts machine.StartOptions) error {
 		}
 	}
 
+	v.recordResources()
 	_, _, err = v.updateTimeStamps(true)
 	return err
 }
@@ -1128,40 +1650,145 @@ func launchWinProxy(v *MachineVM) (bool, string, error) {
 	})
 }
 
-func getWinProxyStateDir(v *MachineVM) (string, error) {
-	dir, err := machine.GetDataDir(vmtype)
-	if err != nil {
-		return "", err
-	}
-	stateDir := filepath.Join(dir, v.Name)
-	if err = os.MkdirAll(stateDir, 0755); err != nil {
-		return "", err
+// launchHealthyWinProxy launches the api proxy and checks that it is still
+// forwarding shortly after, relaunching it a bounded number of times
+func launchHealthyWinProxy(v *MachineVM) (bool, string, error) {
+	var (
+		globalName bool
+		pipeName   string
+		err        error
+	)
+	for attempt := 1; attempt <= winProxyAttempts; attempt++ {
+		globalName, pipeName, err = launchWinProxy(v)
+		if err == nil {
+			time.Sleep(winProxySettle)
+			if err = checkWinProxy(v, strings.TrimPrefix(pipeName, pipePrefix)); err == nil {
+				return globalName, pipeName, nil
+			}
+		}
+		if attempt < winProxyAttempts {
+			logrus.Warnf("API forwarding proxy is not running (attempt %d of %d), restarting it: %v", attempt, winProxyAttempts, err)
+			if stopErr := stopWinProxy(v); stopErr != nil && !errors.Is(stopErr, os.ErrNotExist) {
+				logrus.Debugf("Could not stop API forwarding proxy: %v", stopErr)
+			}
+		}
 	}
-
-	return stateDir, nil
+	return globalName, pipeName, err
 }
 
-func IsWSLInstalled() bool {
+// checkWinProxy reports an error unless the api proxy process of the
+// machine is running and its pipe exists
+func checkWinProxy(v *MachineVM, pipe string) error {
+	pid, _, _, err := readWinProxyTid(v)
+	if err != nil {
+		return err
+	}
+	if active, exitCode := machine.GetProcessState(int(pid)); !active {
+		return fmt.Errorf("win-sshproxy.exe exited with code %d (see windows event logs)", exitCode)
+	}
+	if machine.PipeNameAvailable(pipe) {
+		return fmt.Errorf("api forwarding pipe %s does not exist", pipe)
+	}
+	return nil
+}
+
+func getWinProxyStateDir(v *MachineVM) (string, error) {
+	dir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return "", err
+	}
+	stateDir := filepath.Join(dir, v.Name)
+	if err = os.MkdirAll(stateDir, 0755); err != nil {
+		return "", err
+	}
+
+	return stateDir, nil
+}
+
+// WSLStatus holds the details reported by "wsl --status"
+type WSLStatus struct {
+	// DefaultVersion is the WSL version used for new distributions, 0 if
+	// it was not reported
+	DefaultVersion int
+	// KernelMissing is set when the WSL 2 kernel is not installed
+	KernelMissing bool
+	// WSL2Unsupported is set when the system can only run WSL 1
+	WSL2Unsupported bool
+	// Empty is set when no status was printed at all, in which case
+	// nothing is known about the installation
+	Empty bool
+	// KernelVersion is the version of the WSL 2 kernel, if reported
+	KernelVersion string
+}
+
+// GetWSLStatus runs "wsl --status" and parses its output. An error is
+// returned if the command could not be run or exited with a failure.
+func GetWSLStatus() (*WSLStatus, error) {
 	cmd := SilentExecCmd("wsl", "--status")
 	out, err := cmd.StdoutPipe()
 	cmd.Stderr = nil
 	if err != nil {
-		return false
+		return nil, err
 	}
 	if err = cmd.Start(); err != nil {
-		return false
+		return nil, err
+	}
+	status := parseWSLStatus(transform.NewReader(out, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()))
+	if err := cmd.Wait(); err != nil {
+		return status, err
 	}
-	scanner := bufio.NewScanner(transform.NewReader(out, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()))
-	result := true
+
+	return status, nil
+}
+
+func parseWSLStatus(r io.Reader) *WSLStatus {
+	status := &WSLStatus{Empty: true}
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		line := scanner.Text()
+		// Decoding an empty stream can still yield a stray BOM or NULs
+		line := strings.Trim(scanner.Text(), "\ufeff\x00 \t\r")
+		if len(line) == 0 {
+			continue
+		}
+		status.Empty = false
+
+		switch {
+		case strings.HasPrefix(line, "Default Version:"):
+			status.DefaultVersion, _ = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Default Version:")))
+		case strings.HasPrefix(line, "Kernel version:"):
+			status.KernelVersion = strings.TrimSpace(strings.TrimPrefix(line, "Kernel version:"))
 		// Windows 11 does not set an error exit code when a kernel is not avail
-		if strings.Contains(line, "kernel file is not found") {
-			result = false
-			break
+		case strings.Contains(line, "kernel file is not found"):
+			status.KernelMissing = true
+		case strings.Contains(line, "enable the Virtual Machine Platform"),
+			strings.Contains(line, "WSL 2 is not supported"):
+			status.WSL2Unsupported = true
 		}
 	}
-	if err := cmd.Wait(); !result || err != nil {
+
+	return status
+}
+
+// lookPath finds executables, tests replace it to simulate a missing wsl.exe
+var lookPath = exec.LookPath
+
+// checkWSLExecutable returns an error wrapping machine.ErrWSLNotInstalled
+// when wsl.exe can not be found. Commands run through a missing wsl.exe
+// fail like any other command, which would otherwise be taken to mean
+// that a machine is not running.
+func checkWSLExecutable() error {
+	if _, err := lookPath("wsl"); err != nil {
+		return fmt.Errorf("wsl.exe was not found, run 'podman machine init' to install WSL: %w", machine.ErrWSLNotInstalled)
+	}
+	return nil
+}
+
+func IsWSLInstalled() bool {
+	if checkWSLExecutable() != nil {
+		return false
+	}
+	status, err := GetWSLStatus()
+	if err != nil || status.KernelMissing {
 		return false
 	}
 
@@ -1173,55 +1800,186 @@ func IsWSLFeatureEnabled() bool {
 }
 
 func isWSLRunning(dist string) (bool, error) {
-	cmd := exec.Command("wsl", "-l", "--running", "--quiet")
-	out, err := cmd.StdoutPipe()
+	running, err := getRunningDists()
 	if err != nil {
 		return false, err
 	}
-	if err = cmd.Start(); err != nil {
+	return running[dist], nil
+}
+
+// getRunningDists returns the set of running distributions, so callers
+// checking several machines only need to spawn wsl once
+func getRunningDists() (map[string]bool, error) {
+	return listDists("--running")
+}
+
+// isWSLExist reports whether a distribution is registered with WSL,
+// running or not. WSL compares distribution names case insensitively.
+func isWSLExist(dist string) (bool, error) {
+	dists, err := listDists()
+	if err != nil {
 		return false, err
 	}
-	scanner := bufio.NewScanner(transform.NewReader(out, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()))
-	result := false
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) > 0 && dist == fields[0] {
-			result = true
-			break
+	for name := range dists {
+		if strings.EqualFold(name, dist) {
+			return true, nil
 		}
 	}
+	return false, nil
+}
+
+func listDists(args ...string) (map[string]bool, error) {
+	cmd := exec.Command("wsl", append([]string{"-l", "--quiet"}, args...)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	dists := parseDistList(out)
 
 	_ = cmd.Wait()
 
-	return result, nil
+	return dists, nil
 }
 
+// parseDistList reads the UTF-16 output of wsl -l --quiet
+func parseDistList(r io.Reader) map[string]bool {
+	running := make(map[string]bool)
+	scanner := bufio.NewScanner(transform.NewReader(r, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			running[fields[0]] = true
+		}
+	}
+	return running
+}
+
+// isSystemdRunning reports whether the systemd manager runs in the guest,
+// either in its own namespace or as pid 1. enterns runs commands under it in
+// both cases.
 func isSystemdRunning(dist string) (bool, error) {
-	cmd := exec.Command("wsl", "-u", "root", "-d", dist, "sh")
-	cmd.Stdin = strings.NewReader(sysdpid + "\necho $SYSDPID\n")
-	out, err := cmd.StdoutPipe()
+	cmd := exec.Command("wsl", "-u", "root", "-d", dist, "ps", "-eo", "pid=,args=")
+	out, err := cmd.Output()
 	if err != nil {
-		return false, err
-	}
-	if err = cmd.Start(); err != nil {
-		return false, err
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return false, err
+		}
 	}
-	scanner := bufio.NewScanner(out)
-	result := false
-	if scanner.Scan() {
-		text := scanner.Text()
-		i, err := strconv.Atoi(text)
-		if err == nil && i > 0 {
-			result = true
+	return systemdPid(string(out)) > 0, nil
+}
+
+// systemdPid returns the pid of the systemd manager in the output of
+// "ps -eo pid=,args=", or 0 if it is not running. It matches the manager like
+// systemdPidCmd does in the guest scripts.
+func systemdPid(ps string) int {
+	for _, line := range strings.Split(ps, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[1] != systemdPath {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil && pid > 0 {
+			return pid
 		}
 	}
+	return 0
+}
 
-	_ = cmd.Wait()
+// Logs writes the journal of the current boot of the guest to out. The
+// distribution has to be running, but systemd does not, so the journal can
+// be read after a failed start.
+func (v *MachineVM) Logs(_ string, out io.Writer) error {
+	dist := toDist(v.Name)
+	running, err := isWSLRunning(dist)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf("vm %q is not running, start it before reading its logs", v.Name)
+	}
 
-	return result, nil
+	cmd := exec.Command("wsl", "-u", "root", "-d", dist, "journalctl", "-b", "--no-pager")
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not read the journal of %q: %w", v.Name, err)
+	}
+	return nil
 }
 
-func (v *MachineVM) Stop(name string, _ machine.StopOptions) error {
+// restartPackages are the guest packages that are only picked up by
+// restarting the machine once updated
+var restartPackages = []string{"systemd", "glibc", "podman"}
+
+// UpdateOS upgrades the packages of the running guest with dnf and the WSL
+// kernel with wsl --update, and reports whether restarting the machine is
+// recommended to put the updates into effect
+func (v *MachineVM) UpdateOS(_ string) (bool, error) {
+	if !v.isRunning() {
+		return false, fmt.Errorf("vm %q is not running", v.Name)
+	}
+	dist := toDist(v.Name)
+
+	packagesBefore := queryPackages(dist, restartPackages)
+	if err := wslInvoke(dist, "dnf", "upgrade", "-y"); err != nil {
+		return false, fmt.Errorf("could not upgrade the packages of the guest OS: %w", err)
+	}
+	restart := queryPackages(dist, restartPackages) != packagesBefore
+
+	var kernelBefore string
+	if status, err := GetWSLStatus(); err == nil {
+		kernelBefore = status.KernelVersion
+	}
+	if err := runCmdPassThrough("wsl", "--update"); err != nil {
+		// Updating the kernel can need administrator rights on some
+		// systems, the guest packages are updated regardless
+		logrus.Warnf("Could not update the WSL kernel: %v", err)
+	} else if status, err := GetWSLStatus(); err == nil && status.KernelVersion != kernelBefore {
+		restart = true
+	}
+
+	return restart, nil
+}
+
+// queryPackages returns the installed versions of the given guest packages
+func queryPackages(dist string, packages []string) string {
+	args := append([]string{"-u", "root", "-d", dist, "rpm", "-q"}, packages...)
+	out, _ := exec.Command("wsl", args...).Output()
+	return string(out)
+}
+
+// waitForReady polls the guest until systemd is running and the podman
+// API socket exists, so start does not report success before the machine
+// can serve requests
+func waitForReady(dist string, rootful bool) error {
+	sock := "/run/user/1000/podman/podman.sock"
+	if rootful {
+		sock = "/run/podman/podman.sock"
+	}
+
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		sysd, err := isSystemdRunning(dist)
+		if err != nil {
+			return err
+		}
+		if sysd && exec.Command("wsl", "-u", "root", "-d", dist, "test", "-S", sock).Run() == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if !sysd {
+				return fmt.Errorf("timed out after %s waiting for systemd to start in %q", readyTimeout, dist)
+			}
+			return fmt.Errorf("timed out after %s waiting for the podman socket %s in %q", readyTimeout, sock, dist)
+		}
+		time.Sleep(readyInterval)
+	}
+}
+
+func (v *MachineVM) Stop(name string, opts machine.StopOptions) error {
 	dist := toDist(v.Name)
 
 	wsl, err := isWSLRunning(dist)
@@ -1238,17 +1996,29 @@ func (v *MachineVM) Stop(name string, _ machine.StopOptions) error {
 	}
 
 	if !wsl || !sysd {
+		// The proxy of a machine that went down uncleanly may still run
+		if err := stopWinProxy(v); err == nil {
+			logrus.Debugf("Stopped leftover API forwarding service of %q", v.Name)
+		}
 		return fmt.Errorf("%q is not running", v.Name)
 	}
 
 	_, _, _ = v.updateTimeStamps(true)
 
-	if err := stopWinProxy(v); err != nil {
+	if err := stopWinProxy(v); err != nil && !errors.Is(err, os.ErrNotExist) {
 		fmt.Fprintf(os.Stderr, "Could not stop API forwarding service (win-sshproxy.exe): %s\n", err.Error())
 	}
 
+	timeout := machine.DefaultStopTimeout
+	if opts.Timeout != nil {
+		timeout = *opts.Timeout
+	}
+	if timeout == 0 {
+		return terminateDist(dist)
+	}
+
 	cmd := exec.Command("wsl", "-u", "root", "-d", dist, "sh")
-	cmd.Stdin = strings.NewReader(waitTerm)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(waitTerm, timeout))
 	if err = cmd.Start(); err != nil {
 		return fmt.Errorf("executing wait command: %w", err)
 	}
@@ -1278,12 +2048,21 @@ func (v *MachineVM) State(bypass bool) (machine.Status, error) {
 	return machine.Stopped, nil
 }
 
+// stopWinProxy stops the api proxy recorded in the state directory of the
+// machine. A proxy left behind by an unclean shutdown is stopped the same
+// way, while a recorded process id that now belongs to another program
+// only has its stale record removed.
 func stopWinProxy(v *MachineVM) error {
 	pid, tid, tidFile, err := readWinProxyTid(v)
 	if err != nil {
 		return err
 	}
 
+	if name, err := processImageName(pid); err != nil || !strings.EqualFold(name, winSShProxy) {
+		logrus.Debugf("Removing stale API forwarding record for process %d", pid)
+		return os.Remove(tidFile)
+	}
+
 	proc, err := os.FindProcess(int(pid))
 	if err != nil {
 		return nil
@@ -1335,15 +2114,27 @@ func readWinProxyTid(v *MachineVM) (uint32, uint32, string, error) {
 func (v *MachineVM) Remove(name string, opts machine.RemoveOptions) (string, func() error, error) {
 	var files []string
 
+	// cannot remove a running vm unless --force is used
 	if v.isRunning() {
-		return "", nil, fmt.Errorf("running vm %q cannot be destroyed", v.Name)
+		if !opts.Force {
+			return "", nil, fmt.Errorf("running vm %q cannot be destroyed", v.Name)
+		}
+		if err := v.Stop(v.Name, machine.StopOptions{}); err != nil {
+			return "", nil, err
+		}
 	}
 
 	// Collect all the files that need to be destroyed
 	if !opts.SaveKeys {
 		files = append(files, v.IdentityPath, v.IdentityPath+".pub")
 	}
-	if !opts.SaveImage {
+	// A new machine with the same name has a different host key
+	knownHosts := machine.KnownHostsPath(v.IdentityPath)
+	if _, err := os.Stat(knownHosts); err == nil {
+		files = append(files, knownHosts)
+	}
+	// Imported machines have no image on this host
+	if !opts.SaveImage && v.ImagePath != "" {
 		files = append(files, v.ImagePath)
 	}
 
@@ -1364,8 +2155,27 @@ func (v *MachineVM) Remove(name string, opts machine.RemoveOptions) (string, fun
 		confirmationMessage += msg + "\n"
 	}
 
+	// Saving the image exports the provisioned guest, so podman machine
+	// import can restore it without provisioning a new one
+	savePath := filepath.Join(vmDataDir, "saved", v.Name+".tar")
+	if opts.SaveImage {
+		confirmationMessage += fmt.Sprintf("\nThe WSL distribution %s will be saved to %s and unregistered.\n", toDist(v.Name), savePath)
+	} else {
+		confirmationMessage += fmt.Sprintf("\nThe WSL distribution %s will be unregistered.\n", toDist(v.Name))
+	}
+
 	confirmationMessage += "\n"
 	return confirmationMessage, func() error {
+		if opts.SaveImage {
+			if err := os.MkdirAll(filepath.Dir(savePath), 0755); err != nil {
+				return err
+			}
+			// Nothing is removed when the guest could not be saved
+			if err := v.Export(v.Name, savePath); err != nil {
+				return fmt.Errorf("could not save the WSL distribution: %w", err)
+			}
+			fmt.Printf("Saved the machine to %s, restore it with: podman machine import %s %s\n", savePath, v.Name, savePath)
+		}
 		if err := machine.RemoveConnection(v.Name); err != nil {
 			logrus.Error(err)
 		}
@@ -1384,6 +2194,125 @@ func (v *MachineVM) Remove(name string, opts machine.RemoveOptions) (string, fun
 	}, nil
 }
 
+// Rename changes the name of a stopped machine. WSL can not rename a
+// distribution in place, so it is exported and imported under the new name,
+// and the original is only unregistered once everything else is renamed.
+func (v *MachineVM) Rename(_ string, newName string) (err error) {
+	if v.isRunning() {
+		return fmt.Errorf("running vm %q cannot be renamed", v.Name)
+	}
+	if err := checkDistName(newName); err != nil {
+		return err
+	}
+
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return err
+	}
+	oldName := v.Name
+	oldConfigPath := v.ConfigPath
+	newConfigPath, err := getConfigPath(newName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(newConfigPath); err == nil {
+		return fmt.Errorf("%s: %w", newName, machine.ErrVMAlreadyExists)
+	}
+	distDir := filepath.Join(vmDataDir, "wsldist")
+	newDist := toDist(newName)
+	newDistTarget := filepath.Join(distDir, newName)
+	if exists, err := isWSLExist(newDist); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("WSL distribution %q already exists", newDist)
+	}
+	if _, err := os.Stat(newDistTarget); err == nil {
+		return fmt.Errorf("WSL distribution directory %q already exists", newDistTarget)
+	}
+	// Refuse the rename before changing anything if a file of the new name
+	// is in the way, such as ssh keys kept by rm --save-keys. The api
+	// forwarding state directory is named after the machine too.
+	files := []string{v.ImagePath, v.IdentityPath, v.IdentityPath + ".pub", machine.KnownHostsPath(v.IdentityPath), filepath.Join(vmDataDir, oldName)}
+	if err := machine.CheckRenameMachineFiles(files, oldName, newName); err != nil {
+		return err
+	}
+	if err := machine.CheckRenameConnections(oldName, newName); err != nil {
+		return err
+	}
+
+	// Undo what was changed so far if a later step fails, so the machine
+	// is left under its old name
+	var cleanups []func()
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}()
+
+	exportFile := filepath.Join(distDir, newName+".tar")
+	if err := runCmdPassThrough("wsl", "--export", toDist(oldName), exportFile); err != nil {
+		return fmt.Errorf("the WSL export of guest OS failed: %w", err)
+	}
+	defer func() {
+		if err := os.Remove(exportFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Error(err)
+		}
+	}()
+	if err := importDist(false, newDist, newDistTarget, exportFile); err != nil {
+		return err
+	}
+	cleanups = append(cleanups, func() { cleanupImport(newDist, newDistTarget) })
+
+	newFiles, err := machine.RenameMachineFiles(files, oldName, newName)
+	if err != nil {
+		return err
+	}
+	old := *v
+	cleanups = append(cleanups, func() {
+		*v = old
+		if _, err := machine.RenameMachineFiles(newFiles, newName, oldName); err != nil {
+			logrus.Error(err)
+		}
+	})
+
+	v.Name = newName
+	v.ConfigPath = newConfigPath
+	v.ImagePath = newFiles[0]
+	v.IdentityPath = newFiles[1]
+	if err := v.writeConfig(); err != nil {
+		return err
+	}
+	cleanups = append(cleanups, func() {
+		if err := os.Remove(newConfigPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Error(err)
+		}
+	})
+	if err := machine.RenameConnections(oldName, newName, v.IdentityPath); err != nil {
+		return err
+	}
+	cleanups = append(cleanups, func() {
+		if err := machine.RenameConnections(newName, oldName, old.IdentityPath); err != nil {
+			logrus.Error(err)
+		}
+	})
+	if err := os.Remove(oldConfigPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// The machine is usable under its new name, so failing to remove the
+	// original distribution only leaves it behind
+	if err := runCmdPassThrough("wsl", "--unregister", toDist(oldName)); err != nil {
+		logrus.Errorf("could not unregister the original distribution %s: %v", toDist(oldName), err)
+	}
+	if err := machine.GuardedRemoveAll(filepath.Join(distDir, oldName)); err != nil {
+		logrus.Error(err)
+	}
+	return nil
+}
+
 func (v *MachineVM) isRunning() bool {
 	dist := toDist(v.Name)
 
@@ -1411,6 +2340,9 @@ func (v *MachineVM) SSH(name string, opts machine.SSHOptions) error {
 		return fmt.Errorf("vm %q is not running.", v.Name)
 	}
 
+	if err := machine.CheckMachineUser(opts.Username, v.RemoteUsername); err != nil {
+		return err
+	}
 	username := opts.Username
 	if username == "" {
 		username = v.RemoteUsername
@@ -1419,7 +2351,15 @@ func (v *MachineVM) SSH(name string, opts machine.SSHOptions) error {
 	sshDestination := username + "@localhost"
 	port := strconv.Itoa(v.Port)
 
-	args := []string{"-i", v.IdentityPath, "-p", port, sshDestination, "-o", "UserKnownHostsFile /dev/null", "-o", "StrictHostKeyChecking no"}
+	extraArgs, err := machine.SSHOptionArgs(opts.Options)
+	if err != nil {
+		return err
+	}
+
+	args := append(machine.IdentityArgs(v.IdentityPath), "-p", port)
+	args = append(args, extraArgs...)
+	args = append(args, sshDestination)
+	args = append(args, machine.HostKeyArgs(v.IdentityPath, opts.NoHostKeyCheck)...)
 	if len(opts.Args) > 0 {
 		args = append(args, opts.Args...)
 	} else {
@@ -1433,11 +2373,37 @@ func (v *MachineVM) SSH(name string, opts machine.SSHOptions) error {
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
+	return machine.SSHCommandError(v.Name, cmd.Run())
+}
+
+// Cp copies a file or directory between the host and the machine with scp
+func (v *MachineVM) Cp(_ string, opts machine.CpOptions) error {
+	if !v.isRunning() {
+		return fmt.Errorf("vm %q is not running", v.Name)
+	}
+
+	if err := machine.CheckMachineUser(opts.Username, v.RemoteUsername); err != nil {
+		return err
+	}
+	username := opts.Username
+	if username == "" {
+		username = v.RemoteUsername
+	}
+
+	args := machine.CpArgs(v.IdentityPath, v.Port, username, opts)
+	cmd := exec.Command("scp", args...)
+	logrus.Debugf("Executing: scp %v\n", args)
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
 // List lists all vm's that use qemu virtualization
 func (p *Virtualization) List(_ machine.ListOptions) ([]*machine.ListResponse, error) {
+	if err := checkWSLExecutable(); err != nil {
+		return nil, err
+	}
 	return GetVMInfos()
 }
 
@@ -1449,6 +2415,13 @@ func GetVMInfos() ([]*machine.ListResponse, error) {
 
 	var listed []*machine.ListResponse
 
+	// Look up the running distributions once for the whole list, only the
+	// running ones need to be queried further
+	runningDists, err := getRunningDists()
+	if err != nil {
+		return nil, err
+	}
+
 	if err = filepath.WalkDir(vmConfigDir, func(path string, d fs.DirEntry, err error) error {
 		if strings.HasSuffix(d.Name(), ".json") {
 			path := filepath.Join(vmConfigDir, d.Name())
@@ -1461,15 +2434,20 @@ func GetVMInfos() ([]*machine.ListResponse, error) {
 			listEntry.Name = vm.Name
 			listEntry.Stream = vm.ImageStream
 			listEntry.VMType = "wsl"
-			listEntry.CPUs, _ = getCPUs(vm)
-			listEntry.Memory, _ = getMem(vm)
 			listEntry.DiskSize = getDiskSize(vm)
+			listEntry.DiskUsage = getDiskUsage(vm)
 			listEntry.RemoteUsername = vm.RemoteUsername
 			listEntry.Port = vm.Port
 			listEntry.IdentityPath = vm.IdentityPath
+			listEntry.Rootful = vm.Rootful
 			listEntry.Starting = false
 
-			running := vm.isRunning()
+			dist := toDist(vm.Name)
+			running := false
+			if runningDists[dist] {
+				running, _ = isSystemdRunning(dist)
+			}
+			listEntry.CPUs, listEntry.Memory = vm.resources(running)
 			listEntry.CreatedAt, listEntry.LastUp, _ = vm.updateTimeStamps(running)
 			listEntry.Running = running
 
@@ -1479,6 +2457,9 @@ func GetVMInfos() ([]*machine.ListResponse, error) {
 	}); err != nil {
 		return nil, err
 	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].Name < listed[j].Name
+	})
 	return listed, err
 }
 
@@ -1493,12 +2474,10 @@ func (vm *MachineVM) updateTimeStamps(updateLast bool) (time.Time, time.Time, er
 }
 
 func getDiskSize(vm *MachineVM) uint64 {
-	vmDataDir, err := machine.GetDataDir(vmtype)
+	disk, err := getDiskPath(vm)
 	if err != nil {
 		return 0
 	}
-	distDir := filepath.Join(vmDataDir, "wsldist")
-	disk := filepath.Join(distDir, vm.Name, "ext4.vhdx")
 	info, err := os.Stat(disk)
 	if err != nil {
 		return 0
@@ -1506,11 +2485,38 @@ func getDiskSize(vm *MachineVM) uint64 {
 	return uint64(info.Size())
 }
 
+// getDiskUsage returns the space the sparse disk of the machine actually
+// takes up on the host, or its apparent size if that can not be determined
+func getDiskUsage(vm *MachineVM) uint64 {
+	disk, err := getDiskPath(vm)
+	if err != nil {
+		return 0
+	}
+	usage, err := allocatedFileSize(disk)
+	if err != nil {
+		logrus.Debugf("Falling back to the apparent disk size of machine %q: %v", vm.Name, err)
+		return getDiskSize(vm)
+	}
+	return usage
+}
+
+func getDiskPath(vm *MachineVM) (string, error) {
+	vmDataDir, err := machine.GetDataDir(vmtype)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(vmDataDir, "wsldist", vm.Name, "ext4.vhdx"), nil
+}
+
 func getCPUs(vm *MachineVM) (uint64, error) {
 	dist := toDist(vm.Name)
 	if run, _ := isWSLRunning(dist); !run {
 		return 0, nil
 	}
+	return readCPUs(dist)
+}
+
+func readCPUs(dist string) (uint64, error) {
 	cmd := exec.Command("wsl", "-u", "root", "-d", dist, "nproc")
 	out, err := cmd.StdoutPipe()
 	if err != nil {
@@ -1530,42 +2536,94 @@ func getCPUs(vm *MachineVM) (uint64, error) {
 	return uint64(ret), err
 }
 
-func getMem(vm *MachineVM) (uint64, error) {
+func readMemInfo(vm *MachineVM) (total, available uint64, err error) {
 	dist := toDist(vm.Name)
 	if run, _ := isWSLRunning(dist); !run {
-		return 0, nil
+		return 0, 0, nil
 	}
+	return readDistMemInfo(dist)
+}
+
+func readDistMemInfo(dist string) (total, available uint64, err error) {
 	cmd := exec.Command("wsl", "-u", "root", "-d", dist, "cat", "/proc/meminfo")
 	out, err := cmd.StdoutPipe()
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	if err = cmd.Start(); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	scanner := bufio.NewScanner(out)
-	var (
-		total, available uint64
-		t, a             int
-	)
+	total, available, err = parseMemInfo(out)
+	_ = cmd.Wait()
+	return total, available, err
+}
+
+// resources returns the processors and total memory of the machine. The
+// dist has to be running to query it, so when it is not, or the query
+// fails, the values seen the last time it was running are reported.
+func (v *MachineVM) resources(running bool) (cpus, memory uint64) {
+	cpus, memory = v.CPUs, v.Memory
+	if !running {
+		return
+	}
+	dist := toDist(v.Name)
+	if n, err := readCPUs(dist); err == nil && n > 0 {
+		cpus = n
+	}
+	if total, _, _ := readDistMemInfo(dist); total > 0 {
+		memory = total
+	}
+	return
+}
+
+// recordResources stores the processors and memory visible in the running
+// guest, so they can be reported while the machine is stopped
+func (v *MachineVM) recordResources() {
+	if cpus, err := getCPUs(v); err == nil && cpus > 0 {
+		v.CPUs = cpus
+	}
+	if total, _, _ := readMemInfo(v); total > 0 {
+		v.Memory = total
+	}
+}
+
+// parseMemInfo extracts MemTotal and MemAvailable, in bytes, from the
+// contents of /proc/meminfo. Lines that can not be parsed are skipped, and
+// their errors are returned alongside the values that could be read.
+func parseMemInfo(r io.Reader) (total, available uint64, err error) {
+	var merr *multierror.Error
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		fields := strings.Fields(scanner.Text())
-		if strings.HasPrefix(fields[0], "MemTotal") && len(fields) >= 2 {
-			t, err = strconv.Atoi(fields[1])
-			total = uint64(t) * 1024
-		} else if strings.HasPrefix(fields[0], "MemAvailable") && len(fields) >= 2 {
-			a, err = strconv.Atoi(fields[1])
-			available = uint64(a) * 1024
+		if len(fields) < 2 {
+			continue
 		}
+
+		var target *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			target = &total
+		case "MemAvailable:":
+			target = &available
+		default:
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
 		if err != nil {
-			break
+			merr = multierror.Append(merr, fmt.Errorf("parsing %s: %w", strings.TrimSuffix(fields[0], ":"), err))
+			continue
 		}
+		*target = kb * 1024
+	}
+	if err := scanner.Err(); err != nil {
+		merr = multierror.Append(merr, err)
 	}
-	_ = cmd.Wait()
 
-	return total - available, err
+	return total, available, merr.ErrorOrNil()
 }
 
+// IsValidVMName reports whether a machine called name exists
 func (p *Virtualization) IsValidVMName(name string) (bool, error) {
 	infos, err := GetVMInfos()
 	if err != nil {
@@ -1623,30 +2681,38 @@ func (v *MachineVM) Inspect() (*machine.InspectInfo, error) {
 			ImageStream: v.ImageStream,
 		},
 		LastUp:    lastUp,
+		Mounts:    v.Mounts,
 		Name:      v.Name,
-		Resources: v.getResources(),
+		Resources: v.getResources(state == machine.Running),
+		Rootful:   v.Rootful,
 		SSHConfig: v.SSHConfig,
 		State:     state,
+		VMType:    vmtype.String(),
 	}, nil
 }
 
-func (v *MachineVM) getResources() (resources machine.ResourceConfig) {
-	resources.CPUs, _ = getCPUs(v)
-	resources.Memory, _ = getMem(v)
+func (v *MachineVM) getResources(running bool) (resources machine.ResourceConfig) {
+	resources.CPUs, resources.Memory = v.resources(running)
 	resources.DiskSize = getDiskSize(v)
+	resources.DiskUsage = getDiskUsage(v)
 	return
 }
 
 // RemoveAndCleanMachines removes all machine and cleans up any other files associated with podman machine
 func (p *Virtualization) RemoveAndCleanMachines() error {
 	var (
-		vm             machine.VM
 		listResponse   []*machine.ListResponse
 		opts           machine.ListOptions
 		destroyOptions machine.RemoveOptions
 	)
 	destroyOptions.Force = true
 	var prevErr error
+	record := func(err error) {
+		if prevErr != nil {
+			logrus.Error(prevErr)
+		}
+		prevErr = err
+	}
 
 	listResponse, err := p.List(opts)
 	if err != nil {
@@ -1654,65 +2720,69 @@ func (p *Virtualization) RemoveAndCleanMachines() error {
 	}
 
 	for _, mach := range listResponse {
-		vm, err = p.LoadVMByName(mach.Name)
+		vm, err := p.LoadVMByName(mach.Name)
 		if err != nil {
-			if prevErr != nil {
-				logrus.Error(prevErr)
-			}
-			prevErr = err
+			record(err)
+			continue
 		}
+		// Force stops running machines, whose distributions can not be
+		// unregistered cleanly
 		_, remove, err := vm.Remove(mach.Name, destroyOptions)
 		if err != nil {
-			if prevErr != nil {
-				logrus.Error(prevErr)
-			}
-			prevErr = err
-		} else {
-			if err := remove(); err != nil {
-				if prevErr != nil {
-					logrus.Error(prevErr)
-				}
-				prevErr = err
-			}
+			record(err)
+			continue
+		}
+		if err := remove(); err != nil {
+			record(err)
 		}
 	}
 
 	// Clean leftover files in data dir
 	dataDir, err := machine.DataDirPrefix()
 	if err != nil {
-		if prevErr != nil {
-			logrus.Error(prevErr)
-		}
-		prevErr = err
+		record(err)
 	} else {
-		err := machine.GuardedRemoveAll(dataDir)
-		if err != nil {
-			if prevErr != nil {
-				logrus.Error(prevErr)
-			}
-			prevErr = err
+		unregisterOrphanedDists(filepath.Join(dataDir, vmtype.String(), "wsldist"))
+		if err := machine.GuardedRemoveAll(dataDir); err != nil {
+			record(err)
 		}
 	}
 
 	// Clean leftover files in conf dir
 	confDir, err := machine.ConfDirPrefix()
 	if err != nil {
-		if prevErr != nil {
-			logrus.Error(prevErr)
-		}
-		prevErr = err
+		record(err)
 	} else {
-		err := machine.GuardedRemoveAll(confDir)
-		if err != nil {
-			if prevErr != nil {
-				logrus.Error(prevErr)
-			}
-			prevErr = err
+		if err := machine.GuardedRemoveAll(confDir); err != nil {
+			record(err)
 		}
 	}
 	return prevErr
 }
 
+// unregisterOrphanedDists unregisters the distributions whose storage is
+// still under distDir, which covers machines whose configuration was lost
+// and so were not removed above
+func unregisterOrphanedDists(distDir string) {
+	entries, err := os.ReadDir(distDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dist := toDist(entry.Name())
+		logrus.Debugf("Unregistering leftover WSL distribution %s", dist)
+		if err := exec.Command("wsl", "--terminate", dist).Run(); err != nil {
+			logrus.Debugf("Could not terminate %s: %v", dist, err)
+		}
+		if err := exec.Command("wsl", "--unregister", dist).Run(); err != nil {
+			logrus.Debugf("Could not unregister %s: %v", dist, err)
+		}
+	}
+}
+
 func (p *Virtualization) VMType() machine.VMType {
 	return vmtype
 }
@@ -0,0 +1,678 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestParseMemInfo(t *testing.T) {
+	tests := []struct {
+		name          string
+		meminfo       string
+		wantTotal     uint64
+		wantAvailable uint64
+		wantErr       bool
+	}{
+		{
+			name: "representative with trailing blank line",
+			meminfo: `MemTotal:        8039364 kB
+MemFree:         6974240 kB
+MemAvailable:    7297744 kB
+Buffers:           30772 kB
+Cached:           461660 kB
+HugePages_Total:       0
+
+`,
+			wantTotal:     8039364 * 1024,
+			wantAvailable: 7297744 * 1024,
+		},
+		{
+			name:      "missing MemAvailable",
+			meminfo:   "MemTotal:        1024 kB\nMemFree:          512 kB\n",
+			wantTotal: 1024 * 1024,
+		},
+		{
+			name: "malformed value keeps the rest",
+			meminfo: `MemTotal:        bogus kB
+MemAvailable:    2048 kB
+`,
+			wantAvailable: 2048 * 1024,
+			wantErr:       true,
+		},
+		{
+			name:    "truncated line",
+			meminfo: "MemTotal:\nMemAvailable:    2048 kB\n",
+			// A key without a value is skipped rather than treated as an error
+			wantAvailable: 2048 * 1024,
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			total, available, err := parseMemInfo(strings.NewReader(tt.meminfo))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantTotal, total)
+			assert.Equal(t, tt.wantAvailable, available)
+		})
+	}
+}
+
+func TestResourcesStopped(t *testing.T) {
+	// A stopped machine reports what was recorded, without querying WSL
+	vm := &MachineVM{Name: "test"}
+	vm.CPUs = 4
+	vm.Memory = 8 * 1024 * 1024 * 1024
+	cpus, memory := vm.resources(false)
+	assert.Equal(t, uint64(4), cpus)
+	assert.Equal(t, uint64(8*1024*1024*1024), memory)
+}
+
+func TestVolumesToMounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  string
+		want    machine.Mount
+		wantErr bool
+	}{
+		{
+			name:   "source only",
+			volume: `C:\Users\foo`,
+			want:   machine.Mount{Type: MountTypeBind, Source: `C:\Users\foo`, Target: "/mnt/c/Users/foo"},
+		},
+		{
+			name:   "target",
+			volume: `c:\src:/src`,
+			want:   machine.Mount{Type: MountTypeBind, Source: `c:\src`, Target: "/src"},
+		},
+		{
+			name:   "read only",
+			volume: `D:\data:/data:ro`,
+			want:   machine.Mount{Type: MountTypeBind, Source: `D:\data`, Target: "/data", ReadOnly: true},
+		},
+		{
+			name:   "extended path",
+			volume: `\\?\C:\data:/data`,
+			want:   machine.Mount{Type: MountTypeBind, Source: `\\?\C:\data`, Target: "/data"},
+		},
+		{
+			name:    "relative source",
+			volume:  `data:/data`,
+			wantErr: true,
+		},
+		{
+			name:    "relative target",
+			volume:  `C:\data:data`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown option",
+			volume:  `C:\data:/data:z`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mounts, err := volumesToMounts([]string{tt.volume})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []machine.Mount{tt.want}, mounts)
+		})
+	}
+}
+
+func TestWinToGuestPath(t *testing.T) {
+	for winPath, want := range map[string]string{
+		`C:\`:                  "/mnt/c",
+		`C:\Users\foo\`:        "/mnt/c/Users/foo",
+		`e:/projects/podman`:   "/mnt/e/projects/podman",
+		`\\?\D:\Program Files`: "/mnt/d/Program Files",
+	} {
+		got, err := winToGuestPath(winPath)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, winPath)
+	}
+}
+
+func TestParseWSLStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   WSLStatus
+	}{
+		{
+			name: "installed",
+			status: `Default Distribution: podman-machine-default
+Default Version: 2
+
+Windows Subsystem for Linux was last updated on 5/4/2023
+WSL automatic updates are on.
+
+Kernel version: 5.15.90.1
+`,
+			want: WSLStatus{DefaultVersion: 2, KernelVersion: "5.15.90.1"},
+		},
+		{
+			name: "kernel missing",
+			status: `Default Version: 2
+
+The WSL 2 kernel file is not found. To update or restore the kernel please run 'wsl --update'.
+`,
+			want: WSLStatus{DefaultVersion: 2, KernelMissing: true},
+		},
+		{
+			name: "wsl 1 only",
+			status: `Default Version: 1
+
+Please enable the Virtual Machine Platform Windows feature and ensure virtualization is enabled in the BIOS.
+`,
+			want: WSLStatus{DefaultVersion: 1, WSL2Unsupported: true},
+		},
+		{
+			name:   "empty",
+			status: "",
+			want:   WSLStatus{Empty: true},
+		},
+		{
+			name:   "only a byte order mark",
+			status: "\ufeff\r\n",
+			want:   WSLStatus{Empty: true},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, &tt.want, parseWSLStatus(strings.NewReader(tt.status)))
+		})
+	}
+}
+
+func toUTF16(t testing.TB, s string) string {
+	encoded, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestParseDistList(t *testing.T) {
+	out := toUTF16(t, "podman-machine-default\r\npodman-dev\r\n\r\nUbuntu\r\n")
+	assert.Equal(t, map[string]bool{
+		"podman-machine-default": true,
+		"podman-dev":             true,
+		"Ubuntu":                 true,
+	}, parseDistList(strings.NewReader(out)))
+	assert.Empty(t, parseDistList(strings.NewReader("")))
+}
+
+// BenchmarkListRunningState compares looking up the running state of every
+// listed machine with its own wsl -l --running call against sharing a
+// single call across the list. Each parse stands in for one wsl.exe spawn.
+func BenchmarkListRunningState(b *testing.B) {
+	var names []string
+	var lines strings.Builder
+	for i := 0; i < 10; i++ {
+		names = append(names, fmt.Sprintf("podman-machine-%d", i))
+		if i%2 == 0 {
+			fmt.Fprintf(&lines, "%s\r\n", names[i])
+		}
+	}
+	out := toUTF16(b, lines.String())
+
+	b.Run("per machine", func(b *testing.B) {
+		spawns := 0
+		for i := 0; i < b.N; i++ {
+			for _, name := range names {
+				spawns++
+				_ = parseDistList(strings.NewReader(out))[name]
+			}
+		}
+		b.ReportMetric(float64(spawns)/float64(b.N), "spawns/op")
+	})
+	b.Run("shared", func(b *testing.B) {
+		spawns := 0
+		for i := 0; i < b.N; i++ {
+			spawns++
+			running := parseDistList(strings.NewReader(out))
+			for _, name := range names {
+				_ = running[name]
+			}
+		}
+		b.ReportMetric(float64(spawns)/float64(b.N), "spawns/op")
+	})
+}
+
+func TestValidateWSLConf(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			conf: "# custom settings\n[automount]\noptions = \"metadata\"\n\n[network]\nhostname=dev\n; done\n",
+		},
+		{
+			name: "empty",
+			conf: "",
+		},
+		{
+			name:    "key outside section",
+			conf:    "hostname=dev\n",
+			wantErr: "not in a section",
+		},
+		{
+			name:    "malformed section",
+			conf:    "[network\nhostname=dev\n",
+			wantErr: "malformed section",
+		},
+		{
+			name:    "missing value separator",
+			conf:    "[network]\nhostname\n",
+			wantErr: "expected key=value",
+		},
+		{
+			name:    "reserved user",
+			conf:    "[User]\nDefault = root\n",
+			wantErr: "user.default can not be set",
+		},
+		{
+			name:    "reserved systemd",
+			conf:    "[boot]\nsystemd=true\n",
+			wantErr: "boot.systemd can not be set",
+		},
+		{
+			name:    "reserved resolv.conf generation",
+			conf:    "[network]\ngenerateResolvConf=false\n",
+			wantErr: "use --dns",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWSLConf(tt.conf)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestDNSConf(t *testing.T) {
+	tests := []struct {
+		name       string
+		servers    []string
+		search     []string
+		wantConf   string
+		wantResolv string
+		wantErr    string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:       "servers",
+			servers:    []string{"10.0.0.53", "fd00::53"},
+			wantConf:   "[network]\ngenerateResolvConf=false\n",
+			wantResolv: "nameserver 10.0.0.53\nnameserver fd00::53\n",
+		},
+		{
+			name:       "servers and search",
+			servers:    []string{"10.0.0.53"},
+			search:     []string{"corp.example.com", "example.com"},
+			wantConf:   "[network]\ngenerateResolvConf=false\n",
+			wantResolv: "nameserver 10.0.0.53\nsearch corp.example.com example.com\n",
+		},
+		{
+			name:    "search without servers",
+			search:  []string{"example.com"},
+			wantErr: "require at least one DNS server",
+		},
+		{
+			name:    "hostname server",
+			servers: []string{"dns.example.com"},
+			wantErr: "not an IP address",
+		},
+		{
+			name:    "search with whitespace",
+			servers: []string{"10.0.0.53"},
+			search:  []string{"corp example.com"},
+			wantErr: "invalid DNS search domain",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf, resolv, err := dnsConf(tt.servers, tt.search)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantConf, conf)
+			assert.Equal(t, tt.wantResolv, resolv)
+		})
+	}
+}
+
+func TestSniffRootfsFormat(t *testing.T) {
+	tarHeader := make([]byte, rootfsSniffLen)
+	copy(tarHeader[257:], "ustar\x0000")
+
+	tests := []struct {
+		name    string
+		header  []byte
+		wantErr string
+	}{
+		{name: "tar", header: tarHeader},
+		{name: "xz", header: []byte("\xfd7zXZ\x00\x00\x04")},
+		{name: "gzip", header: []byte{0x1f, 0x8b, 0x08, 0x00}},
+		{name: "zstd", header: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}},
+		{name: "qcow2", header: []byte("QFI\xfb\x00\x00\x00\x03"), wantErr: "qcow2 disk image"},
+		{name: "vhdx", header: []byte("vhdxfile"), wantErr: "vhdx disk image"},
+		{name: "unknown", header: []byte("#!/bin/sh\n"), wantErr: "not a tar archive"},
+		{name: "empty", header: nil, wantErr: "not a tar archive"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := sniffRootfsFormat("image", tt.header)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTimeZoneRegex(t *testing.T) {
+	for _, tz := range []string{"UTC", "America/Chicago", "America/Argentina/Buenos_Aires", "Etc/GMT+5", "Etc/GMT-14"} {
+		assert.True(t, timeZoneRegex.MatchString(tz), tz)
+	}
+	for _, tz := range []string{"", "/etc/passwd", "../../etc/passwd", "America/Chicago; reboot", "Europe//Paris", "Europe/"} {
+		assert.False(t, timeZoneRegex.MatchString(tz), tz)
+	}
+}
+
+func TestCheckWSLExecutable(t *testing.T) {
+	defer func(orig func(string) (string, error)) { lookPath = orig }(lookPath)
+
+	lookPath = func(file string) (string, error) {
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	assert.ErrorIs(t, checkWSLExecutable(), machine.ErrWSLNotInstalled)
+	assert.False(t, IsWSLInstalled())
+	_, err := (&Virtualization{}).List(machine.ListOptions{})
+	assert.ErrorIs(t, err, machine.ErrWSLNotInstalled)
+	_, err = (&Virtualization{}).LoadVMByName("test")
+	assert.ErrorIs(t, err, machine.ErrWSLNotInstalled)
+
+	lookPath = func(file string) (string, error) {
+		return `C:\Windows\System32\wsl.exe`, nil
+	}
+	assert.NoError(t, checkWSLExecutable())
+}
+
+func TestDistOwner(t *testing.T) {
+	machines := []*machine.ListResponse{{Name: "podman-machine-default"}, {Name: "dev"}, {Name: "podman"}}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "test"},
+		{name: "dev"},
+		{name: "podman-dev", want: "dev"},
+		{name: "Podman-Dev"},
+		{name: "machine-default", want: "podman-machine-default"},
+		{name: "podman"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, distOwner(tt.name, machines), tt.name)
+	}
+}
+
+func TestClassifyImportFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		wantKnown     bool
+		wantTransient bool
+	}{
+		{
+			name:          "service not available",
+			output:        "The operation could not be started because a required feature is not installed.\r\nError code: Wsl/Service/RegisterDistro/CreateVm/HCS/HCS_E_SERVICE_NOT_AVAILABLE\r\n",
+			wantKnown:     true,
+			wantTransient: true,
+		},
+		{
+			name:      "platform not enabled",
+			output:    "Please enable the Virtual Machine Platform Windows feature and ensure virtualization is enabled in the BIOS.\r\nError code: Wsl/Service/RegisterDistro/CreateVm/HCS/HCS_E_HYPERV_NOT_INSTALLED\r\n",
+			wantKnown: true,
+		},
+		{
+			name:      "disk full",
+			output:    "There is not enough space on the disk.\r\n",
+			wantKnown: true,
+		},
+		{
+			name:   "unknown",
+			output: "The system cannot find the file specified.\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure, known := classifyImportFailure(tt.output)
+			assert.Equal(t, tt.wantKnown, known)
+			assert.Equal(t, tt.wantTransient, failure.transient)
+		})
+	}
+}
+
+func TestDecodeWSLOutput(t *testing.T) {
+	assert.Equal(t, "Import in progress\r\n", decodeWSLOutput([]byte(toUTF16(t, "Import in progress\r\n"))))
+	assert.Equal(t, "Import in progress\r\n", decodeWSLOutput([]byte("Import in progress\r\n")))
+	assert.Equal(t, "", decodeWSLOutput(nil))
+}
+
+func TestIsFedoraRelease(t *testing.T) {
+	for _, s := range []string{"35", "38", "testing"} {
+		assert.True(t, isFedoraRelease(s), s)
+	}
+	for _, s := range []string{"", "-1", "+38", "stable", `C:\images\rootfs.tar.xz`, "https://example.com/rootfs.tar.xz"} {
+		assert.False(t, isFedoraRelease(s), s)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	const sum = "3f786850e387550fdab836ed7e6dc881de23001b8b6a5a8ea43e6c7ae3ea0dfc"
+	assert.Equal(t, sum, parseChecksum(sum))
+	assert.Equal(t, sum, parseChecksum(strings.ToUpper(sum)+"  rootfs.tar.xz\n"))
+	assert.Empty(t, parseChecksum(""))
+	assert.Empty(t, parseChecksum("not found"))
+	assert.Empty(t, parseChecksum(sum[:40]))
+}
+
+func TestCachedChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+	assert.NoError(t, os.WriteFile(path, []byte("first"), 0644))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	first, err := cachedChecksum(path, info)
+	assert.NoError(t, err)
+	assert.FileExists(t, path+checksumSuffix)
+
+	// Same length, different content, modified after the checksum was stored
+	assert.NoError(t, os.WriteFile(path, []byte("other"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(path, later, later))
+	info, err = os.Stat(path)
+	assert.NoError(t, err)
+	second, err := cachedChecksum(path, info)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestParseImageURL(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantURL string
+		wantErr bool
+	}{
+		{path: `C:\images\rootfs.tar.xz`},
+		{path: "rootfs.tar.xz"},
+		{path: "https://example.com/images/rootfs.tar.xz", wantURL: "https://example.com/images/rootfs.tar.xz"},
+		{path: "http://10.0.0.1:8080/rootfs.tar", wantURL: "http://10.0.0.1:8080/rootfs.tar"},
+		{path: "ftp://example.com/rootfs.tar.xz", wantErr: true},
+		{path: "file:///C:/images/rootfs.tar.xz", wantErr: true},
+		{path: "https:///rootfs.tar.xz", wantErr: true},
+		{path: "https://example.com/", wantErr: true},
+	}
+	for _, tt := range tests {
+		u, err := parseImageURL(tt.path)
+		if tt.wantErr {
+			assert.Error(t, err, tt.path)
+			continue
+		}
+		assert.NoError(t, err, tt.path)
+		if tt.wantURL == "" {
+			assert.Nil(t, u, tt.path)
+		} else if assert.NotNil(t, u, tt.path) {
+			assert.Equal(t, tt.wantURL, u.String())
+		}
+	}
+}
+
+func TestProbeImageURL(t *testing.T) {
+	modified := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rootfs.tar.xz":
+			http.ServeContent(w, r, "rootfs.tar.xz", modified, strings.NewReader("image"))
+		case "/nohead.tar.xz":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/rootfs.tar.xz")
+	size, lastModified, err := probeImageURL(u)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+	assert.True(t, modified.Equal(lastModified))
+
+	u, _ = url.Parse(srv.URL + "/nohead.tar.xz")
+	size, _, err = probeImageURL(u)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), size)
+
+	u, _ = url.Parse(srv.URL + "/missing.tar.xz")
+	_, _, err = probeImageURL(u)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestSystemdPid(t *testing.T) {
+	tests := []struct {
+		name string
+		ps   string
+		want int
+	}{
+		{
+			name: "nested namespace",
+			ps: `    1 /init
+   12 unshare --kill-child --fork --pid --mount --mount-proc --propagation shared /lib/systemd/systemd
+   13 /lib/systemd/systemd-journald
+   14 /lib/systemd/systemd
+`,
+			want: 14,
+		},
+		{
+			name: "pid 1",
+			ps: `    1 /lib/systemd/systemd --system
+   45 /lib/systemd/systemd-udevd
+`,
+			want: 1,
+		},
+		{
+			name: "services only",
+			ps: `    1 /init
+   13 /lib/systemd/systemd-journald
+`,
+			want: 0,
+		},
+		{
+			name: "empty",
+			ps:   "",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, systemdPid(tt.ps))
+		})
+	}
+}
+
+// setWinVersion makes winVersionAtLeast compare against version for the
+// duration of the test
+func setWinVersion(t *testing.T, version [3]uint32) {
+	hostWinVersion()
+	saved := winVersion
+	winVersion = version
+	t.Cleanup(func() { winVersion = saved })
+}
+
+func TestWinVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		name string
+		host [3]uint32
+		want [3]uint32
+		ok   bool
+	}{
+		{name: "equal", host: [3]uint32{10, 0, 19041}, want: [3]uint32{10, 0, 19041}, ok: true},
+		{name: "newer build", host: [3]uint32{10, 0, 22000}, want: [3]uint32{10, 0, 19041}, ok: true},
+		{name: "older build", host: [3]uint32{10, 0, 18362}, want: [3]uint32{10, 0, 19041}, ok: false},
+		{name: "newer minor, older build", host: [3]uint32{10, 1, 0}, want: [3]uint32{10, 0, 99999}, ok: true},
+		{name: "older minor, newer build", host: [3]uint32{10, 0, 99999}, want: [3]uint32{10, 1, 0}, ok: false},
+		{name: "newer major, older minor and build", host: [3]uint32{11, 0, 0}, want: [3]uint32{10, 3, 22000}, ok: true},
+		{name: "older major, newer minor and build", host: [3]uint32{6, 3, 99999}, want: [3]uint32{10, 0, 0}, ok: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			setWinVersion(t, tt.host)
+			assert.Equal(t, tt.ok, winVersionAtLeast(uint(tt.want[0]), uint(tt.want[1]), uint(tt.want[2])))
+		})
+	}
+}
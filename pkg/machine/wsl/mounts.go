@@ -0,0 +1,108 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/containers/podman/v4/pkg/machine"
+)
+
+// MountTypeBind is used for volumes on WSL machines. Windows drives are
+// already exposed to the guest under /mnt/<drive>, so volumes are bind
+// mounted from there rather than shared through a separate filesystem.
+const MountTypeBind = "bind"
+
+var driveLetterMatcher = regexp.MustCompile(`^(?:\\\\[.?]\\)?[a-zA-Z]$`)
+
+// volumesToMounts parses --volume values of the form
+// source[:target[:options]], where source is a Windows path
+func volumesToMounts(volumes []string) ([]machine.Mount, error) {
+	mounts := make([]machine.Mount, 0, len(volumes))
+	for _, volume := range volumes {
+		paths := strings.SplitN(volume, ":", 3)
+		if len(paths) > 1 && driveLetterMatcher.MatchString(paths[0]) {
+			paths = strings.SplitN(volume, ":", 4)
+			paths = append([]string{paths[0] + ":" + paths[1]}, paths[2:]...)
+		}
+
+		guestSource, err := winToGuestPath(paths[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid volume %q: %w", volume, err)
+		}
+		target := guestSource
+		if len(paths) > 1 && len(paths[1]) > 0 {
+			target = paths[1]
+		}
+		if !path.IsAbs(target) {
+			return nil, fmt.Errorf("invalid volume %q: target %q must be an absolute path", volume, target)
+		}
+
+		readonly := false
+		if len(paths) > 2 {
+			for _, o := range strings.Split(paths[2], ",") {
+				switch o {
+				case "rw":
+					readonly = false
+				case "ro":
+					readonly = true
+				default:
+					return nil, fmt.Errorf("invalid volume %q: unknown option %q", volume, o)
+				}
+			}
+		}
+
+		mounts = append(mounts, machine.Mount{Type: MountTypeBind, Source: paths[0], Target: target, ReadOnly: readonly})
+	}
+	return mounts, nil
+}
+
+// winToGuestPath translates a Windows path into the path WSL exposes it
+// under, e.g. C:\foo becomes /mnt/c/foo
+func winToGuestPath(winPath string) (string, error) {
+	p := strings.TrimPrefix(winPath, `\\?\`)
+	if len(p) < 2 || p[1] != ':' || !driveLetterMatcher.MatchString(p[:1]) {
+		return "", errors.New("source must be an absolute Windows path including the drive letter")
+	}
+	rest := strings.ReplaceAll(p[2:], `\`, "/")
+	return path.Join("/mnt", strings.ToLower(p[:1]), "/"+rest), nil
+}
+
+// mountVolumes bind mounts the machine's volumes into the namespace
+// systemd, and therefore podman, runs in
+func mountVolumes(v *MachineVM, dist string, quiet bool) error {
+	for _, mount := range v.Mounts {
+		if mount.Type != MountTypeBind {
+			return fmt.Errorf("unknown mount type: %s", mount.Type)
+		}
+		source, err := winToGuestPath(mount.Source)
+		if err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Printf("Mounting volume... %s:%s\n", mount.Source, mount.Target)
+		}
+
+		script := "set -e\n"
+		script += fmt.Sprintf("test -d %[1]s || { echo %[1]s is not accessible in the guest >&2; exit 1; }\n", shellQuote(source))
+		if source != mount.Target {
+			script += fmt.Sprintf("mkdir -p %[2]s && mountpoint -q %[2]s || mount --bind %[1]s %[2]s\n", shellQuote(source), shellQuote(mount.Target))
+		}
+		if mount.ReadOnly {
+			script += fmt.Sprintf("mount -o remount,bind,ro %s\n", shellQuote(mount.Target))
+		}
+		if err := wslInvoke(dist, "/usr/local/bin/enterns", "sh", "-c", script); err != nil {
+			return fmt.Errorf("could not mount volume %s:%s: %w", mount.Source, mount.Target, err)
+		}
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,76 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// rootfsSniffLen covers the tar magic at offset 257
+const rootfsSniffLen = 512
+
+var (
+	tarMagic = []byte("ustar")
+
+	// compressedMagics are the compressions an image can be delivered in,
+	// the archive inside is checked once decompressed by wsl --import
+	compressedMagics = [][]byte{
+		{0xfd, '7', 'z', 'X', 'Z', 0x00}, // xz
+		{0x1f, 0x8b},                     // gzip
+		{0x28, 0xb5, 0x2f, 0xfd},         // zstd
+		[]byte("BZh"),                    // bzip2
+		[]byte("PK\x03\x04"),             // zip
+	}
+
+	// diskImageMagics identify disk images used by other providers
+	diskImageMagics = []struct {
+		name  string
+		magic []byte
+	}{
+		{"qcow2", []byte("QFI\xfb")},
+		{"vhdx", []byte("vhdxfile")},
+		{"vhd", []byte("conectix")},
+	}
+)
+
+// checkRootfsFormat returns an error if the local image at path is not a,
+// possibly compressed, tar archive of a root filesystem, the only format
+// wsl --import accepts
+func checkRootfsFormat(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, rootfsSniffLen)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not read image %s: %w", path, err)
+	}
+	return sniffRootfsFormat(path, header[:n])
+}
+
+func sniffRootfsFormat(path string, header []byte) error {
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(header, magic) {
+			return nil
+		}
+	}
+	if len(header) >= 257+len(tarMagic) && bytes.Equal(header[257:257+len(tarMagic)], tarMagic) {
+		return nil
+	}
+
+	const hint = "WSL machines need a root filesystem tarball (.tar, .tar.xz, .tar.gz, ...)"
+	for _, disk := range diskImageMagics {
+		if bytes.HasPrefix(header, disk.magic) {
+			return fmt.Errorf("image %s is a %s disk image, which is only supported by other machine providers: %s", path, disk.name, hint)
+		}
+	}
+	return fmt.Errorf("image %s is not a tar archive: %s", path, hint)
+}
@@ -0,0 +1,51 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/containers/podman/v4/pkg/machine"
+	"github.com/sirupsen/logrus"
+)
+
+// timeZoneRegex matches tz database names such as America/Chicago or
+// Etc/GMT+5, which also keeps them safe to use in a shell command
+var timeZoneRegex = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+// timeZoneWSLConf stops WSL from replacing the configured time zone with
+// the Windows one when the machine starts
+const timeZoneWSLConf = "[time]\nuseWindowsTimezone=false\n"
+
+// guestTimeZone returns the tz database name to configure in the machine
+// for a --timezone value. An empty name is returned when the host time
+// zone can not be detected, leaving it to WSL.
+func guestTimeZone(tz string) (string, error) {
+	if len(tz) == 0 {
+		return "", nil
+	}
+	resolved, err := machine.ResolveTimeZone(tz)
+	if err != nil || len(resolved) == 0 {
+		logrus.Warnf("Could not detect the host time zone, leaving the machine time zone to WSL: %v", err)
+		return "", nil
+	}
+	if !timeZoneRegex.MatchString(resolved) {
+		return "", fmt.Errorf("invalid time zone %q", resolved)
+	}
+	return resolved, nil
+}
+
+// configureTimeZone points /etc/localtime of the guest at tz, which must
+// exist in the tz database of the guest
+func configureTimeZone(dist string, tz string) error {
+	zoneFile := "/usr/share/zoneinfo/" + tz
+	if err := wslInvoke(dist, "test", "-f", zoneFile); err != nil {
+		return fmt.Errorf("unknown time zone %q, it is not in the tz database of the guest OS", tz)
+	}
+	if err := wslPipe(fmt.Sprintf("ln -sf ../usr/share/zoneinfo/%s /etc/localtime\n", tz), dist, "sh"); err != nil {
+		return fmt.Errorf("could not set the time zone of the guest OS: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containers/podman/v4/pkg/machine"
+)
+
+// URLDownload fetches a root filesystem image from a URL given as the
+// image path, and caches it like the Fedora images
+type URLDownload struct {
+	machine.Download
+	// modified is when the server last changed the image, zero if unknown
+	modified time.Time
+}
+
+// parseImageURL returns the URL an image path names, or nil for a local
+// path. URLs other than http and https are rejected rather than taken for
+// a file name.
+func parseImageURL(imagePath string) (*url.URL, error) {
+	if !strings.Contains(imagePath, "://") {
+		return nil, nil
+	}
+	imageURL, err := url.Parse(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL %q: %w", imagePath, err)
+	}
+	if imageURL.Scheme != "http" && imageURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q in image URL %s: only http and https are supported", imageURL.Scheme, imagePath)
+	}
+	if len(imageURL.Host) == 0 {
+		return nil, fmt.Errorf("invalid image URL %q: missing host", imagePath)
+	}
+	if name := path.Base(imageURL.Path); name == "/" || name == "." {
+		return nil, fmt.Errorf("invalid image URL %q: must name an image file", imagePath)
+	}
+	return imageURL, nil
+}
+
+func NewURLDownloader(vmType machine.VMType, vmName string, imageURL *url.URL) (machine.DistributionDownload, error) {
+	size, modified, err := probeImageURL(imageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDir, err := machine.GetCacheDir(vmType)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := machine.GetDataDir(vmType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Images from different URLs can share a file name
+	sum := sha256.Sum256([]byte(imageURL.String()))
+	imageName := path.Base(imageURL.Path)
+	cacheName := fmt.Sprintf("url-%s-%s", hex.EncodeToString(sum[:6]), imageName)
+
+	u := URLDownload{
+		Download: machine.Download{
+			Arch:      machine.DetermineMachineArch(),
+			Artifact:  machine.None,
+			CacheDir:  cacheDir,
+			Format:    machine.Tar,
+			ImageName: imageName,
+			LocalPath: filepath.Join(cacheDir, cacheName),
+			URL:       imageURL,
+			VMName:    vmName,
+			Size:      size,
+		},
+		modified: modified,
+	}
+	u.Download.LocalUncompressedFile = u.GetLocalUncompressedFile(dataDir)
+	return u, nil
+}
+
+func (u URLDownload) Get() *machine.Download {
+	return &u.Download
+}
+
+// HasUsableCache reuses a cached image that has the size the server
+// reports and is not older than the image on the server
+func (u URLDownload) HasUsableCache() (bool, error) {
+	info, err := os.Stat(u.LocalPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if u.Size <= 0 || info.Size() != u.Size {
+		return false, nil
+	}
+	return u.modified.IsZero() || !info.ModTime().Before(u.modified), nil
+}
+
+func (u URLDownload) CleanCache() error {
+	// Set cached image to expire after 2 weeks
+	expire := 14 * 24 * time.Hour
+	return machine.RemoveImageAfterExpire(u.CacheDir, expire)
+}
+
+// probeImageURL checks that the image can be downloaded before anything
+// else is done, and returns its size and modification time where the
+// server reports them
+func probeImageURL(imageURL *url.URL) (int64, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), machine.HTTPTimeout())
+	defer cancel()
+
+	resp, err := httpRequest(ctx, http.MethodHead, imageURL.String())
+	if err != nil {
+		return -1, time.Time{}, fmt.Errorf("could not reach image URL %s: %w", imageURL, machine.TimeoutError(err))
+	}
+	_ = resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		// The server does not answer HEAD, leave it to the download
+		return -1, time.Time{}, nil
+	default:
+		return -1, time.Time{}, fmt.Errorf("could not download image URL %s: %s", imageURL, resp.Status)
+	}
+
+	modified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		modified = time.Time{}
+	}
+	return resp.ContentLength, modified, nil
+}
@@ -11,10 +11,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 	"unicode/utf16"
 	"unsafe"
 
+	"github.com/containers/podman/v4/pkg/machine"
 	"github.com/containers/storage/pkg/homedir"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/windows"
@@ -72,22 +75,59 @@ const (
 	WM_QUIT                         = 0x12
 )
 
-func winVersionAtLeast(major uint, minor uint, build uint) bool {
-	var out [3]uint32
+// elevatedWaitTimeout bounds the wait for the elevated child, which covers
+// approving the privilege request as well as slow dism operations
+const elevatedWaitTimeout = 30 * time.Minute
+
+var (
+	winVersionOnce sync.Once
+	// winVersion is the major, minor and build number of the host, which
+	// can not change while podman runs. Tests set it after completing
+	// winVersionOnce.
+	winVersion [3]uint32
+)
 
-	in := []uint32{uint32(major), uint32(minor), uint32(build)}
-	out[0], out[1], out[2] = windows.RtlGetNtVersionNumbers()
+// hostWinVersion returns the version of the host, looked up once
+func hostWinVersion() [3]uint32 {
+	winVersionOnce.Do(func() {
+		winVersion[0], winVersion[1], winVersion[2] = windows.RtlGetNtVersionNumbers()
+	})
+	return winVersion
+}
+
+// winVersionAtLeast reports whether the host runs at least the given
+// version of Windows
+func winVersionAtLeast(major uint, minor uint, build uint) bool {
+	return versionAtLeast(hostWinVersion(), [3]uint32{uint32(major), uint32(minor), uint32(build)})
+}
 
-	for i, o := range out {
-		if in[i] > o {
-			return false
+// versionAtLeast compares versions as tuples, where the first component
+// that differs decides
+func versionAtLeast(have, want [3]uint32) bool {
+	for i := range want {
+		if have[i] != want[i] {
+			return have[i] > want[i]
 		}
-		if in[i] < o {
+	}
+	return true
+}
+
+// wslDisabledByPolicy reports whether group policy, set through the WSL
+// policy keys, forbids using WSL or installing the inbox WSL feature
+func wslDisabledByPolicy() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Policies\WSL`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+
+	for _, name := range []string{"AllowWSL", "AllowInboxWSL"} {
+		if value, _, err := k.GetIntegerValue(name); err == nil && value == 0 {
+			logrus.Debugf("WSL policy %s is disabled", name)
 			return true
 		}
 	}
-
-	return true
+	return false
 }
 
 func hasAdminRights() bool {
@@ -146,7 +186,7 @@ func relaunchElevatedWait() error {
 	if ret, _, _ := procShellExecuteEx.Call(uintptr(unsafe.Pointer(info))); ret == 0 { // 0 = False
 		err := syscall.GetLastError()
 		if info.hInstApp == SE_ERR_ACCESSDENIED {
-			return wrapMaybe(err, "request to elevate privileges was denied")
+			return fmt.Errorf("request to elevate privileges was denied (%v): %w", err, machine.ErrAdminRequired)
 		}
 		return wrapMaybef(err, "could not launch process, ShellEX Error = %d", info.hInstApp)
 	}
@@ -154,10 +194,12 @@ func relaunchElevatedWait() error {
 	handle := syscall.Handle(info.hProcess)
 	defer syscall.CloseHandle(handle)
 
-	w, err := syscall.WaitForSingleObject(handle, syscall.INFINITE)
+	w, err := syscall.WaitForSingleObject(handle, uint32(elevatedWaitTimeout.Milliseconds()))
 	switch w {
 	case syscall.WAIT_OBJECT_0:
 		break
+	case syscall.WAIT_TIMEOUT:
+		return fmt.Errorf("elevated process did not finish within %s, it may be waiting on a privilege prompt or a stuck installation", elevatedWaitTimeout)
 	case syscall.WAIT_FAILED:
 		return fmt.Errorf("could not wait for process, failed: %w", err)
 	default:
@@ -340,6 +382,24 @@ func buildCommandArgs(elevate bool) string {
 	return strings.Join(args, " ")
 }
 
+// processImageName returns the executable file name of a running process
+func processImageName(pid uint32) (string, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = windows.CloseHandle(handle)
+	}()
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(handle, 0, &buf[0], &size); err != nil {
+		return "", err
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:size])), nil
+}
+
 func sendQuit(tid uint32) {
 	user32 := syscall.NewLazyDLL("user32.dll")
 	postMessage := user32.NewProc("PostThreadMessageW")
@@ -359,3 +419,23 @@ func SilentExecCmd(command string, args ...string) *exec.Cmd {
 	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: 0x08000000}
 	return cmd
 }
+
+// allocatedFileSize returns the space a file takes up on disk, which for
+// sparse or compressed files is less than its size
+func allocatedFileSize(path string) (uint64, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	procGetCompressedFileSize := kernel32.NewProc("GetCompressedFileSizeW")
+
+	var high uint32
+	low, _, err := procGetCompressedFileSize.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&high)))
+	// INVALID_FILE_SIZE is only an error when the last error is set, as it
+	// is also a valid low order size
+	if uint32(low) == 0xFFFFFFFF && err != syscall.Errno(0) {
+		return 0, fmt.Errorf("could not get the allocated size of %s: %w", path, err)
+	}
+	return uint64(high)<<32 | uint64(uint32(low)), nil
+}
@@ -0,0 +1,104 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// reservedWSLConfKeys are managed by podman machine and can not be set by a
+// custom wsl.conf, the value explains why
+var reservedWSLConfKeys = map[string]string{
+	"user.default": "the default user is the machine username",
+	"boot.systemd": "systemd is started by the machine bootstrap",
+	"boot.command": "the machine bootstrap runs at boot",
+
+	"network.generateresolvconf": "use --dns and --dns-search to configure name resolution",
+	"time.usewindowstimezone":    "use --timezone to configure the time zone",
+}
+
+// resolvConfSetup replaces the resolv.conf WSL links to with a plain file
+const resolvConfSetup = "rm -f /etc/resolv.conf && cat > /etc/resolv.conf"
+
+// readWSLConf reads a custom wsl.conf to add to the one podman machine
+// writes, returning an empty string when no path is given
+func readWSLConf(path string) (string, error) {
+	if len(path) == 0 {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read wsl.conf: %w", err)
+	}
+	if err := validateWSLConf(string(content)); err != nil {
+		return "", fmt.Errorf("invalid wsl.conf %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// validateWSLConf checks that content is made of INI sections, key=value
+// pairs, comments and blank lines, and that it does not set reserved keys
+func validateWSLConf(content string) error {
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(text) == 0, strings.HasPrefix(text, "#"), strings.HasPrefix(text, ";"):
+			continue
+		case strings.HasPrefix(text, "["):
+			if !strings.HasSuffix(text, "]") || len(text) < 3 {
+				return fmt.Errorf("line %d: malformed section %q", line, text)
+			}
+			section = strings.ToLower(strings.TrimSpace(text[1 : len(text)-1]))
+			continue
+		}
+
+		key, _, found := strings.Cut(text, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !found || len(key) == 0 {
+			return fmt.Errorf("line %d: expected key=value, got %q", line, text)
+		}
+		if len(section) == 0 {
+			return fmt.Errorf("line %d: key %q is not in a section", line, key)
+		}
+		if reason, ok := reservedWSLConfKeys[section+"."+key]; ok {
+			return fmt.Errorf("line %d: %s.%s can not be set, %s", line, section, key, reason)
+		}
+	}
+	return scanner.Err()
+}
+
+// dnsConf returns the wsl.conf settings and resolv.conf content that make
+// the machine use the given DNS servers and search domains instead of the
+// resolv.conf generated by WSL, or empty strings when none are given
+func dnsConf(servers, search []string) (string, string, error) {
+	if len(servers) == 0 {
+		if len(search) > 0 {
+			return "", "", fmt.Errorf("DNS search domains require at least one DNS server")
+		}
+		return "", "", nil
+	}
+
+	var resolv strings.Builder
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return "", "", fmt.Errorf("invalid DNS server %q: not an IP address", server)
+		}
+		fmt.Fprintf(&resolv, "nameserver %s\n", server)
+	}
+	for _, domain := range search {
+		if len(domain) == 0 || strings.ContainsAny(domain, " \t\n#;") {
+			return "", "", fmt.Errorf("invalid DNS search domain %q", domain)
+		}
+	}
+	if len(search) > 0 {
+		fmt.Fprintf(&resolv, "search %s\n", strings.Join(search, " "))
+	}
+	return "[network]\ngenerateResolvConf=false\n", resolv.String(), nil
+}
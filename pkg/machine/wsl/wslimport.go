@@ -0,0 +1,128 @@
+//go:build windows
+// +build windows
+
+package wsl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// importAttempts bounds how often a transient wsl --import failure is retried
+const importAttempts = 3
+
+// importRetryDelay is the wait before the first retry, doubled for each
+// further attempt
+var importRetryDelay = 2 * time.Second
+
+type importFailure struct {
+	// match is a lower case fragment of the wsl --import output. Error
+	// codes are preferred since the messages are localized.
+	match string
+	hint  string
+	// transient failures are typical for a WSL install that has not
+	// fully settled and usually go away on retry
+	transient bool
+}
+
+var importFailures = []importFailure{
+	{"hcs_e_service_not_available", "the Virtual Machine Platform is not ready yet, a reboot may be required", true},
+	{"hcs_e_connection_timeout", "the WSL virtual machine did not start in time", true},
+	{"rpc_s_call_failed", "the WSL service stopped responding", true},
+	{"hcs_e_hyperv_not_installed", "the Virtual Machine Platform is not enabled, enable it and make sure virtualization is enabled in the BIOS, then reboot", false},
+	{"virtual machine platform", "the Virtual Machine Platform is not ready, a reboot may be required", false},
+	{"requires an update to its kernel component", "the WSL kernel is missing, run 'wsl --update'", false},
+	{"error_disk_full", "there is not enough free disk space to import the machine image", false},
+	{"not enough space on the disk", "there is not enough free disk space to import the machine image", false},
+	{"error_already_exists", "a WSL distribution with the machine name already exists, rerun init with --force", false},
+}
+
+// classifyImportFailure finds a known cause in the output of a failed
+// wsl --import
+func classifyImportFailure(output string) (importFailure, bool) {
+	output = strings.ToLower(output)
+	for _, f := range importFailures {
+		if strings.Contains(output, f.match) {
+			return f, true
+		}
+	}
+	return importFailure{}, false
+}
+
+// decodeWSLOutput converts output of wsl.exe, which writes UTF-16 unless
+// WSL_UTF8 is set, to a string
+func decodeWSLOutput(b []byte) string {
+	isUTF16 := len(b) > 1 && (b[1] == 0 || (b[0] == 0xff && b[1] == 0xfe))
+	if !isUTF16 {
+		return string(b)
+	}
+	decoded, _, err := transform.Bytes(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder(), b)
+	if err != nil {
+		return string(b)
+	}
+	return string(decoded)
+}
+
+// importDist runs wsl --import, retrying failures that are known to be
+// transient with an exponential backoff. A distribution left registered
+// by the failed attempts is unregistered before returning the error.
+func importDist(quiet bool, dist, distTarget, imagePath string) error {
+	delay := importRetryDelay
+	for attempt := 1; ; attempt++ {
+		output, err := runImport(quiet, dist, distTarget, imagePath)
+		if err == nil {
+			return nil
+		}
+
+		failure, known := classifyImportFailure(output)
+		if known && failure.transient && attempt < importAttempts {
+			logrus.Warnf("WSL import failed, %s: retrying in %s", failure.hint, delay)
+			cleanupImport(dist, distTarget)
+			time.Sleep(delay)
+			delay *= 2
+			continue
+		}
+
+		cleanupImport(dist, distTarget)
+		if known {
+			return fmt.Errorf("the WSL import of guest OS failed, %s: %w", failure.hint, err)
+		}
+		return fmt.Errorf("the WSL import of guest OS failed: %w", err)
+	}
+}
+
+func runImport(quiet bool, dist, distTarget, imagePath string) (string, error) {
+	var out bytes.Buffer
+	logrus.Debugf("Running command: wsl --import %s %s %s --version 2", dist, distTarget, imagePath)
+	cmd := exec.Command("wsl", "--import", dist, distTarget, imagePath, "--version", "2")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, &out)
+	if quiet {
+		cmd.Stdout = &out
+	}
+	cmd.Stderr = io.MultiWriter(os.Stderr, &out)
+	err := cmd.Run()
+	return decodeWSLOutput(out.Bytes()), err
+}
+
+// cleanupImport removes what a failed import may have left behind, so that
+// neither a retry nor a later init trips over it
+func cleanupImport(dist, distTarget string) {
+	if exists, err := isWSLExist(dist); err == nil && exists {
+		if err := exec.Command("wsl", "--unregister", dist).Run(); err != nil {
+			logrus.Warnf("could not unregister %s: %v", dist, err)
+		}
+	}
+	if err := os.RemoveAll(distTarget); err != nil {
+		logrus.Debugf("could not remove %s: %v", distTarget, err)
+	}
+}
@@ -50,10 +50,19 @@ func ConvertWinMountPath(path string) (string, error) {
 	case strings.HasPrefix(path, `\\.\`):
 		path = "/mnt/wsl/" + path[4:]
 	case len(path) > 1 && path[1] == ':':
-		path = "/mnt/" + strings.ToLower(path[0:1]) + path[2:]
+		if err := verifyWinPath(path); err != nil {
+			return path, err
+		}
+		return toGuestDrivePath(path), nil
 	default:
 		return path, errors.New("unsupported UNC path")
 	}
 
 	return strings.ReplaceAll(path, `\`, "/"), nil
 }
+
+// toGuestDrivePath maps a Windows drive path to where WSL mounts the drive,
+// e.g. C:\Users\me becomes /mnt/c/Users/me
+func toGuestDrivePath(path string) string {
+	return "/mnt/" + strings.ToLower(path[0:1]) + strings.ReplaceAll(path[2:], `\`, "/")
+}
@@ -1,6 +1,7 @@
 package specgen
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/containers/common/pkg/machine"
@@ -19,6 +20,24 @@ func resolveRelativeOnWindows(path string) string {
 	return path
 }
 
+// winPathExists checks whether a Windows drive path exists in the WSL
+// guest, where the Windows drives are mounted under /mnt
 func winPathExists(path string) bool {
-	return false
+	if !shouldResolveWinPaths() || !hasWinDriveScheme(path, 0) {
+		return false
+	}
+	_, err := os.Stat(toGuestDrivePath(path))
+	return err == nil
+}
+
+// verifyWinPath confirms a Windows drive path exists in the WSL guest before
+// it is mounted. Drives that are not mounted under /mnt can not be checked.
+func verifyWinPath(path string) error {
+	if _, err := os.Stat(toGuestDrivePath(path[:2])); err != nil {
+		return nil
+	}
+	if !winPathExists(path) {
+		return fmt.Errorf("%s (%s in the WSL guest): %w", path, toGuestDrivePath(path), os.ErrNotExist)
+	}
+	return nil
 }
@@ -18,3 +18,7 @@ func resolveRelativeOnWindows(path string) string {
 func winPathExists(path string) bool {
 	return false
 }
+
+func verifyWinPath(path string) error {
+	return nil
+}
@@ -28,3 +28,7 @@ func winPathExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+func verifyWinPath(path string) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package specgen
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitVolumeStringWindows(t *testing.T) {
+	tests := []struct {
+		vol  string
+		want []string
+	}{
+		{`C:\data:/data`, []string{`C:\data`, "/data"}},
+		{`C:/data:/data:ro`, []string{`C:/data`, "/data", "ro"}},
+		{`c:/Users/me/src:/src`, []string{`c:/Users/me/src`, "/src"}},
+		{`\\?\C:\data:/data`, []string{`\\?\C:\data`, "/data"}},
+		{`\\server\share:/data`, []string{`\\server\share`, "/data"}},
+		{`/c/data:/data`, []string{"/c/data", "/data"}},
+		{`myvolume:/data`, []string{"myvolume", "/data"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SplitVolumeString(tt.vol), tt.vol)
+	}
+}
+
+func TestConvertWinMountPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: `C:\Windows\System32`, want: "/mnt/c/Windows/System32"},
+		{path: `C:/Windows/System32`, want: "/mnt/c/Windows/System32"},
+		{path: `C:\`, want: "/mnt/c/"},
+		{path: `\\?\C:\Windows`, want: "/mnt/c/Windows"},
+		{path: `/c/Users/me`, want: "/mnt/c/Users/me"},
+		{path: `\\server\share`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ConvertWinMountPath(tt.path)
+		if tt.wantErr {
+			assert.Error(t, err, tt.path)
+			continue
+		}
+		assert.NoError(t, err, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+}
+
+func TestGenVolumeMountsWindowsIsSilent(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	mounts, _, _, err := GenVolumeMounts([]string{`C:\data:/data`})
+	os.Stdout = stdout
+	require.NoError(t, w.Close())
+	require.NoError(t, err)
+
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, string(output))
+
+	require.Contains(t, mounts, "/data")
+	assert.Equal(t, `C:\data`, mounts["/data"].Source)
+}